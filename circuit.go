@@ -0,0 +1,84 @@
+package quicklyHttps
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitOpenError 表示目标主机的熔断器处于打开状态，请求被直接拒绝
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for host %s until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// circuitState 单个主机的熔断状态
+type circuitState struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// circuitBreaker 按主机统计连续失败次数，达到阈值后在冷却时间内拒绝请求，
+// 冷却结束后进入半开状态，只放行一个探测请求，成功则关闭，失败则重新打开
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	hosts     map[string]*circuitState
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, hosts: make(map[string]*circuitState)}
+}
+
+// allow 判断是否允许向该主机发送请求
+func (b *circuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.hosts[host]
+	if !ok || state.failures < b.threshold {
+		return nil
+	}
+	if now().Before(state.openUntil) || state.probing {
+		return &CircuitOpenError{Host: host, Until: state.openUntil}
+	}
+	state.probing = true
+	return nil
+}
+
+// record 记录一次请求的结果
+func (b *circuitBreaker) record(host string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.hosts[host]
+	if !ok {
+		state = &circuitState{}
+		b.hosts[host] = state
+	}
+	state.probing = false
+	if success {
+		state.failures = 0
+		return
+	}
+	state.failures++
+	if state.failures >= b.threshold {
+		state.openUntil = now().Add(b.cooldown)
+	}
+}
+
+// SetCircuitBreaker 为每个主机启用熔断器，连续失败 failureThreshold 次（传输层错误或 5xx）后，
+// 在 cooldown 时间内直接返回 *CircuitOpenError，之后放行一个请求探测服务是否恢复，
+// failureThreshold <= 0 表示关闭熔断器
+func (c *Client) SetCircuitBreaker(failureThreshold int, cooldown time.Duration) *Client {
+	if failureThreshold <= 0 {
+		c.circuitBreaker = nil
+	} else {
+		c.circuitBreaker = newCircuitBreaker(failureThreshold, cooldown)
+	}
+	return c
+}
@@ -0,0 +1,65 @@
+package quicklyHttps
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetCircuitBreaker(t *testing.T) {
+	clk := useFakeClock(t)
+	var hits, healthy int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}).SetRetryMax(1).SetCircuitBreaker(3, time.Minute)
+	other := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if resp.StatusCode() != http.StatusServiceUnavailable {
+			t.Fatalf("request %d: status %d", i, resp.StatusCode())
+		}
+	}
+
+	_, err := c.R().Execute()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("got error %v, want *CircuitOpenError", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("server saw %d requests, want open circuit to fail fast", n)
+	}
+	if _, err := c.R().Execute(other.BaseURL); err != nil {
+		t.Errorf("request to another host failed: %v", err)
+	}
+
+	// 冷却结束后放行的探测请求失败，熔断器重新打开
+	clk.Advance(time.Minute)
+	if resp, err := c.R().Execute(); err != nil || resp.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("probe got %v, %v; want the 503 passed through", resp, err)
+	}
+	if _, err := c.R().Execute(); !errors.As(err, &openErr) {
+		t.Errorf("got error %v after failed probe, want circuit reopened", err)
+	}
+
+	// 服务恢复后探测成功，熔断器关闭
+	atomic.StoreInt32(&healthy, 1)
+	clk.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().Execute()
+		if err != nil || resp.StatusCode() != http.StatusOK {
+			t.Fatalf("request %d after recovery: %v, %v", i, resp, err)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 7 {
+		t.Errorf("server saw %d requests, want 7", n)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/net/publicsuffix"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 	"net/http"
 	"net/http/cookiejar"
 	urlpkg "net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -25,38 +28,74 @@ type Client struct {
 	Timeout                 time.Duration                          // 请求超时
 	Logger                  LeveledLogger                          // 日志记录器
 	RetryMax                int                                    // 最大重试次数
+	TimeoutRetryMax         int                                    // 超时错误的最大重试次数, 负数表示与 RetryMax 一致
+	MaxRetryElapsedTime     time.Duration                          // 重试的最长累计耗时, 0 表示不限制
+	RetryAfterCap           time.Duration                          // 遵循 Retry-After 时的最长等待时间
+	RetryOnTruncatedBody    bool                                   // 响应体被截断时是否重试
 	Cookies                 []*http.Cookie                         // 每个请求都要发送的 cookie
 	Header                  http.Header                            // 每个请求都要发送的头部
+	DefaultHeaders          http.Header                            // 请求未设置时才使用的默认头部
 	QueryParams             map[string]string                      // 请求的查询参数
+	BaseURLQueryParams      map[string]string                      // 构建 URL 时合并到每个请求的查询参数
+	QueryArrayFormat        ArrayFormat                            // 数组查询参数的编码方式
 	Body                    string                                 // 请求的主体内容
 	FormParams              urlpkg.Values                          // 表单参数
 	Debug                   bool                                   // 是否启用调试模式
+	MaxBodyLogSize          int                                    // 日志中请求体和响应体的最大字节数, 0 表示不限制
+	AutoReferer             bool                                   // 重定向时是否自动设置 Referer, 默认启用
+	RedirectPreserveMethod  bool                                   // 重定向时是否保留原始请求方法和请求体
+	AutoIdempotencyKey      bool                                   // 是否为 POST/PATCH 请求自动生成 Idempotency-Key
+	SingleFlight            bool                                   // 是否合并相同的并发 GET 请求
+	MethodOverride          bool                                   // 是否将 PUT/PATCH/DELETE 请求以 POST 发送并通过头部携带实际方法
+	StreamMode              bool                                   // 流式模式, 不限制总耗时, Timeout 作为单次读取的超时
+	ReadHeadBody            bool                                   // HEAD 请求的响应是否读取响应体
+	DefaultCharset          string                                 // 文本类型 Content-Type 默认追加的字符集
+	ResponseCharsetFallback string                                 // 响应未声明字符集且不是 UTF-8 时使用的字符集
 	loggerInit              sync.Once                              // 用于初始化日志记录器
 	UserInfo                *User                                  // 用户信息, 用于请求认证
 	handleRequestResultFunc HandleRequestResult                    // 处理请求结果的函数
+	handleRequestErrorFunc  HandleRequestError                     // 处理请求失败的函数
+	checkRedirect           HandleRedirect                         // 用户设置的重定向策略
+	responseValidators      []ResponseValidator                    // 按顺序执行的响应校验函数
 	jsonMarshal             func(v interface{}) ([]byte, error)    // JSON 编码器
 	jsonUnmarshal           func(data []byte, v interface{}) error // JSON 解码器
 	xmlMarshal              func(v interface{}) ([]byte, error)    // XML 编码器
 	xmlUnmarshal            func(data []byte, v interface{}) error // XML 解码器
+	retryBudget             *retryBudget                           // 客户端级别的重试预算
+	backoff                 Backoff                                // 重试等待策略
+	retryableStatus         map[int]bool                           // 需要重试的响应状态码
+	circuitBreaker          *circuitBreaker                        // 按主机熔断
+	concurrency             *semaphore.Weighted                    // 限制同时进行的请求数
+	sensitiveHeaders        map[string]bool                        // 日志中需要脱敏的头部
+	proxyRotation           *proxyRotation                         // 轮询使用的代理池
+	singleFlight            singleflight.Group                     // 合并相同的并发请求
+	headerOrder             *headerOrder                           // 请求头的发送顺序
 }
 
 // NewClient 使用默认设置创建一个新的 Client
 func NewClient() *Client {
 	c := &Client{
-		RetryMax:       retryMax,
-		AuthScheme:     defaultAuthScheme,
-		BasicAuthToken: defaultHeaderAuthorizationKey,
-		Header:         make(http.Header),
-		Cookies:        make([]*http.Cookie, 0),
-		Logger:         newStandardLogger(),
-		QueryParams:    make(map[string]string),
-		FormParams:     make(urlpkg.Values),
-		Timeout:        30 * time.Second,
-		jsonMarshal:    json.Marshal,
-		jsonUnmarshal:  json.Unmarshal,
-		xmlMarshal:     xml.Marshal,
-		xmlUnmarshal:   xml.Unmarshal,
+		RetryMax:                retryMax,
+		TimeoutRetryMax:         -1,
+		AutoReferer:             true,
+		AuthScheme:              defaultAuthScheme,
+		BasicAuthToken:          defaultHeaderAuthorizationKey,
+		Header:                  make(http.Header),
+		DefaultHeaders:          make(http.Header),
+		Cookies:                 make([]*http.Cookie, 0),
+		Logger:                  newStandardLogger(),
+		QueryParams:             make(map[string]string),
+		BaseURLQueryParams:      make(map[string]string),
+		FormParams:              make(urlpkg.Values),
+		Timeout:                 30 * time.Second,
+		ResponseCharsetFallback: defaultResponseCharset,
+		RetryAfterCap:           defaultRetryAfterCap,
+		jsonMarshal:             json.Marshal,
+		jsonUnmarshal:           json.Unmarshal,
+		xmlMarshal:              xml.Marshal,
+		xmlUnmarshal:            xml.Unmarshal,
 	}
+	c.SetSensitiveHeaders(defaultSensitiveHeaders...)
 	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	c.Client = &http.Client{
 		Jar:     jar,
@@ -68,13 +107,40 @@ func NewClient() *Client {
 	return c
 }
 
+// SetHTTPClient 替换底层的 *http.Client，客户端的请求头、重试、超时等设置保持不变。
+// client 未设置 Jar 或 Transport 时沿用原有的 CookieJar 和 Transport（包括 oauth、tracing 等包装），
+// client 自带的 CheckRedirect 作为 SetCheckRedirect 的策略与库内置的重定向处理组合使用，传入的 client 本身不会被修改
+func (c *Client) SetHTTPClient(client *http.Client) *Client {
+	if client == nil {
+		c.logger().Error("nil http.Client, setting ignored")
+		return c
+	}
+	hc := *client
+	if hc.Jar == nil {
+		hc.Jar = c.Client.Jar
+	}
+	if hc.Transport == nil {
+		hc.Transport = c.Client.Transport
+	}
+	if hc.Transport == nil {
+		hc.Transport = createTransport(nil)
+	}
+	if hc.CheckRedirect != nil && client != c.Client {
+		c.checkRedirect = hc.CheckRedirect
+	}
+	hc.CheckRedirect = c.handleRedirect
+	c.Client = &hc
+	return c
+}
+
 // SetProxyURL 设置代理服务器 URL
 func (c *Client) SetProxyURL(proxy string) *Client {
 	proxyURL, ok := urlpkg.Parse(proxy)
 	if ok != nil {
 		c.logger().Error("invalid proxy URL", "error", ok)
-	} else {
-		c.Client.Transport.(*http.Transport).Proxy = http.ProxyURL(proxyURL)
+	} else if t := c.transport(); t != nil {
+		t.Proxy = http.ProxyURL(proxyURL)
+		c.proxyRotation = nil
 	}
 	return c
 }
@@ -116,9 +182,62 @@ func (c *Client) SetHandleRequestResultFunc(f HandleRequestResult) *Client {
 	return c
 }
 
+// OnError 设置请求失败时的回调，每次传输层返回错误（包括每次重试）都会调用
+func (c *Client) OnError(f HandleRequestError) *Client {
+	c.handleRequestErrorFunc = f
+	return c
+}
+
 // SetCheckRedirect 设置重定向函数
 func (c *Client) SetCheckRedirect(f func(req *http.Request, via []*http.Request) error) *Client {
-	c.Client.CheckRedirect = f
+	c.checkRedirect = f
+	c.Client.CheckRedirect = c.handleRedirect
+	return c
+}
+
+// AddResponseValidator 添加一个响应校验函数，所有校验函数在收到响应后按添加顺序执行，
+// 遇到第一个错误即停止，请求返回 *ValidationError 且不会重试
+func (c *Client) AddResponseValidator(validator ResponseValidator) *Client {
+	c.responseValidators = append(c.responseValidators, validator)
+	return c
+}
+
+// SetLogLevel 设置默认日志记录器的日志级别，低于该级别的日志将被丢弃
+// 自定义的 Logger 需要自行处理日志级别
+func (c *Client) SetLogLevel(level Level) *Client {
+	if l, ok := c.logger().(*standardLogger); ok {
+		l.SetLevel(level)
+	}
+	return c
+}
+
+// SetDefaultCharset 设置默认字符集，库设置的文本类型 Content-Type 会追加 "; charset=xxx"
+// 二进制类型不受影响，传入空字符串表示不追加
+func (c *Client) SetDefaultCharset(charset string) *Client {
+	c.DefaultCharset = charset
+	return c
+}
+
+// SetResponseCharsetFallback 设置 DetectEncoding 的回退字符集，响应未声明字符集且内容不是合法的 UTF-8 时使用，
+// 默认为 gbk，传入空字符串表示不做转换
+func (c *Client) SetResponseCharsetFallback(charset string) *Client {
+	c.ResponseCharsetFallback = charset
+	return c
+}
+
+// contentType 返回库设置的 Content-Type，文本类型按需追加默认字符集
+func (c *Client) contentType(mediaType string) string {
+	if c.DefaultCharset == "" || !isTextContentType(mediaType) {
+		return mediaType
+	}
+	return mediaType + "; charset=" + c.DefaultCharset
+}
+
+// When 在 cond 为 true 时调用 fn 配置客户端，便于在链式调用中按条件设置
+func (c *Client) When(cond bool, fn func(*Client)) *Client {
+	if cond {
+		fn(c)
+	}
 	return c
 }
 
@@ -128,6 +247,49 @@ func (c *Client) SetDebug(debug bool) *Client {
 	return c
 }
 
+// SetMaxBodyLogSize 设置日志中记录的请求体和响应体的最大字节数，超出部分会被截断，0 表示不限制
+func (c *Client) SetMaxBodyLogSize(size int) *Client {
+	c.MaxBodyLogSize = size
+	return c
+}
+
+// SetSensitiveHeaders 设置日志中需要脱敏的头部，其值会显示为 ***，
+// 默认为 Authorization、Cookie、Set-Cookie，调用后替换默认列表
+func (c *Client) SetSensitiveHeaders(keys ...string) *Client {
+	c.sensitiveHeaders = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		c.sensitiveHeaders[http.CanonicalHeaderKey(key)] = true
+	}
+	return c
+}
+
+// logHeaders 将头部转换为易读的格式，敏感头部的值会被脱敏
+func (c *Client) logHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if c.sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			headers[key] = redacted
+		} else {
+			headers[key] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
+
+// logCookies 将 cookie 转换为易读的格式，headerKey 为敏感头部时 cookie 的值会被脱敏
+func (c *Client) logCookies(cookies []*http.Cookie, headerKey string) []string {
+	sensitive := c.sensitiveHeaders[headerKey]
+	result := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		value := cookie.Value
+		if sensitive {
+			value = redacted
+		}
+		result[i] = fmt.Sprintf("%s=%s", cookie.Name, value)
+	}
+	return result
+}
+
 // SetUserAgent 设置 User-Agent 头
 func (c *Client) SetUserAgent(userAgent string) *Client {
 	return c.SetHeader("User-Agent", userAgent)
@@ -143,12 +305,134 @@ func (c *Client) SetRetryMax(retryMax int) *Client {
 	return c
 }
 
+// SetTimeoutRetryMax 单独设置超时错误的最大重试次数，例如对非幂等请求设置为 0 以避免超时后重复提交，
+// 其它错误仍按 RetryMax 重试，负数表示与 RetryMax 一致
+func (c *Client) SetTimeoutRetryMax(n int) *Client {
+	c.TimeoutRetryMax = n
+	return c
+}
+
+// SetBackoff 设置重试前的等待策略，可使用内置的 ConstantBackoff、LinearBackoff、ExponentialBackoff
+// 或自行实现 Backoff 接口，传入 nil 表示重试前不等待
+func (c *Client) SetBackoff(backoff Backoff) *Client {
+	c.backoff = backoff
+	return c
+}
+
+// SetMaxRetryElapsedTime 设置重试的最长累计耗时（包含等待时间），
+// 超过该时间后即使还有剩余重试次数也不再重试，0 表示不限制
+func (c *Client) SetMaxRetryElapsedTime(d time.Duration) *Client {
+	c.MaxRetryElapsedTime = d
+	return c
+}
+
+// SetRetryBudget 设置客户端级别的重试预算，重试次数不超过请求总数的 ratio 倍，
+// 预算耗尽后请求失败时不再重试，ratio <= 0 表示不限制
+func (c *Client) SetRetryBudget(ratio float64) *Client {
+	if ratio <= 0 {
+		c.retryBudget = nil
+	} else {
+		c.retryBudget = newRetryBudget(ratio)
+	}
+	return c
+}
+
+// SetRetryOnTruncatedBody 设置响应体长度与 Content-Length 不一致时是否重试，
+// 启用后响应体会在 Execute 中被读取并缓存
+func (c *Client) SetRetryOnTruncatedBody(retry bool) *Client {
+	c.RetryOnTruncatedBody = retry
+	return c
+}
+
+// SetRetryAfterCap 设置遵循响应 Retry-After 头部时的最长等待时间，避免服务端返回过大的值导致长时间等待，
+// 默认 2 分钟，0 表示不限制
+func (c *Client) SetRetryAfterCap(max time.Duration) *Client {
+	c.RetryAfterCap = max
+	return c
+}
+
+// SetRetryableStatusCodes 设置需要重试的响应状态码，替换之前的设置，默认不按状态码重试。
+// 重试次数用尽时返回最后一次的响应
+func (c *Client) SetRetryableStatusCodes(codes ...int) *Client {
+	c.retryableStatus = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		c.retryableStatus[code] = true
+	}
+	return c
+}
+
+// AddRetryableStatusCode 添加一个需要重试的响应状态码
+func (c *Client) AddRetryableStatusCode(code int) *Client {
+	if c.retryableStatus == nil {
+		c.retryableStatus = make(map[int]bool)
+	}
+	c.retryableStatus[code] = true
+	return c
+}
+
+// SetAutoIdempotencyKey 设置是否为未指定 Idempotency-Key 的 POST/PATCH 请求自动生成一个，
+// 同一请求的多次重试使用相同的值
+func (c *Client) SetAutoIdempotencyKey(enable bool) *Client {
+	c.AutoIdempotencyKey = enable
+	return c
+}
+
+// SetDisableResponseBodyForHEAD 设置是否忽略 HEAD 请求的响应体，默认忽略，
+// 避免服务端错误地在 HEAD 响应中发送响应体时 Body() 读取或阻塞；传入 false 时按普通响应读取
+func (c *Client) SetDisableResponseBodyForHEAD(disable bool) *Client {
+	c.ReadHeadBody = !disable
+	return c
+}
+
+// SetMethodOverride 设置是否将 PUT/PATCH/DELETE 请求改为 POST 发送，实际方法通过 X-HTTP-Method-Override 头部传递，
+// 用于拦截这些方法的防火墙或代理
+func (c *Client) SetMethodOverride(enable bool) *Client {
+	c.MethodOverride = enable
+	return c
+}
+
+// SetSingleFlight 设置是否合并方法、URL 和请求头都相同的并发 GET/HEAD 请求，只发出一次实际请求并共享响应，
+// 仅适用于幂等且可缓存的请求。Authorization、Cookie 等请求头不同的请求不会被合并
+func (c *Client) SetSingleFlight(enable bool) *Client {
+	c.SingleFlight = enable
+	return c
+}
+
+// SetMaxConcurrentRequests 限制共享该客户端的所有 goroutine 同时进行的请求数，
+// 达到上限时阻塞等待，请求的 context 被取消时返回其错误，n <= 0 表示不限制
+func (c *Client) SetMaxConcurrentRequests(n int) *Client {
+	if n <= 0 {
+		c.concurrency = nil
+	} else {
+		c.concurrency = semaphore.NewWeighted(int64(n))
+	}
+	return c
+}
+
 // SetBaseURL 设置基础 URL
 func (c *Client) SetBaseURL(baseURL string) *Client {
 	c.BaseURL = strings.TrimSuffix(baseURL, "/")
 	return c
 }
 
+// SetBaseURLFromEnv 从环境变量 key 读取基础 URL，环境变量为空时使用 fallback，
+// 两者都为空或不是合法的绝对 URL 时记录日志并保持原有的基础 URL
+func (c *Client) SetBaseURLFromEnv(key string, fallback ...string) *Client {
+	baseURL := os.Getenv(key)
+	if baseURL == "" && len(fallback) > 0 {
+		baseURL = fallback[0]
+	}
+	if baseURL == "" {
+		c.logger().Warn("base URL environment variable is empty", "key", key)
+		return c
+	}
+	if !isAbsoluteURL(baseURL) {
+		c.logger().Error("invalid base URL", "key", key, "url", baseURL)
+		return c
+	}
+	return c.SetBaseURL(baseURL)
+}
+
 // SetHeader 设置单个请求头部
 func (c *Client) SetHeader(key, value string) *Client {
 	c.Header.Set(key, value)
@@ -163,6 +447,15 @@ func (c *Client) SetHeaders(headers map[string]string) *Client {
 	return c
 }
 
+// SetDefaultHeaders 设置默认头部，仅在请求没有设置同名头部时使用，
+// 与 SetHeaders 不同，请求可以通过 SetHeader 覆盖这些值
+func (c *Client) SetDefaultHeaders(headers map[string]string) *Client {
+	for key, value := range headers {
+		c.DefaultHeaders.Set(key, value)
+	}
+	return c
+}
+
 // SetBody 设置请求体
 func (c *Client) SetBody(body string) *Client {
 	c.Body = body
@@ -189,6 +482,18 @@ func (c *Client) SetCookieRaw(cookie *http.Cookie) *Client {
 	return c
 }
 
+// SetCookieWithAttributes 解析 Set-Cookie 格式的字符串并添加每个请求都要发送的 cookie，
+// 属性会被保留，发送时按 Secure、Domain、Path 判断是否携带
+func (c *Client) SetCookieWithAttributes(setCookie string) *Client {
+	cookie, err := parseSetCookie(setCookie)
+	if err != nil {
+		c.logger().Error("failed to parse cookie", "error", err)
+		return c
+	}
+	c.Cookies = append(c.Cookies, cookie)
+	return c
+}
+
 // SetQueryParams 设置多个查询参数
 func (c *Client) SetQueryParams(params map[string]string) *Client {
 	for key, value := range params {
@@ -203,6 +508,28 @@ func (c *Client) SetQueryParam(key, value string) *Client {
 	return c
 }
 
+// SetBaseURLQueryParams 设置多个每个请求都会携带的查询参数
+func (c *Client) SetBaseURLQueryParams(params map[string]string) *Client {
+	for key, value := range params {
+		c.SetBaseURLQueryParam(key, value)
+	}
+	return c
+}
+
+// SetBaseURLQueryParam 设置每个请求都会携带的查询参数，
+// 在构建 URL 时合并，请求自身设置的同名参数优先
+func (c *Client) SetBaseURLQueryParam(key, value string) *Client {
+	c.BaseURLQueryParams[key] = value
+	return c
+}
+
+// SetQueryArrayFormat 设置数组查询参数的编码方式：
+// ArrayFormatRepeat（a=1&a=2，默认）、ArrayFormatBrackets（a[]=1&a[]=2）、ArrayFormatComma（a=1,2）
+func (c *Client) SetQueryArrayFormat(format ArrayFormat) *Client {
+	c.QueryArrayFormat = format
+	return c
+}
+
 // SetFormParams 设置多个表单参数
 func (c *Client) SetFormParams(params map[string]string) *Client {
 	for key, value := range params {
@@ -218,15 +545,16 @@ func (c *Client) SetFormParam(key, value string) *Client {
 }
 
 func (c *Client) R() *Request {
-	if c.Method == "" {
-		c.Method = http.MethodGet
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
 	}
 	return &Request{
 		rawClient:   c,
-		method:      c.Method,
+		method:      method,
 		body:        c.Body,
 		Header:      c.Header.Clone(),
-		startedAt:   time.Now(),
+		startedAt:   now(),
 		queryParams: copyMap(c.QueryParams),
 		formParams:  copyValues(c.FormParams),
 		cookies:     append([]*http.Cookie{}, c.Cookies...),
@@ -258,21 +586,77 @@ func (c *Client) SetMethod(method string) *Client {
 }
 
 func (r *Request) Do() (*Response, error) {
-	if r.rawClient.Timeout > 0 {
-		r.rawClient.Client.Timeout = r.rawClient.Timeout
+	client := r.rawClient.Client
+	timeout := client.Timeout
+	if r.rawClient.StreamMode {
+		timeout = 0
+	} else if r.rawClient.Timeout > 0 {
+		timeout = r.rawClient.Timeout
+	}
+	if r.stream && timeout != 0 {
+		// 单个请求的流式模式使用副本取消总耗时限制，不影响同一客户端的其它请求
+		streamClient := *client
+		streamClient.Timeout = 0
+		client = &streamClient
+	} else if client.Timeout != timeout {
+		// 只在设置变化时写入，避免并发请求同时写入同一个 http.Client
+		client.Timeout = timeout
+	}
+	if sem := r.rawClient.concurrency; sem != nil {
+		if err := sem.Acquire(r.Request.Context(), 1); err != nil {
+			return nil, err
+		}
+		defer sem.Release(1)
+	}
+	breaker := r.rawClient.circuitBreaker
+	if breaker != nil {
+		if err := breaker.allow(r.Request.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	req := r.Request
+	var choice *proxyChoice
+	if r.rawClient.proxyRotation != nil {
+		req, choice = withProxyChoice(req)
+	}
+	var idle *idleTimeout
+	if r.streaming() && r.rawClient.Timeout > 0 {
+		req, idle = withIdleTimeout(req, r.rawClient.Timeout)
+	}
+	var capture *connCapture
+	if r.rawClient.headerOrder != nil {
+		req, capture = withConnCapture(req)
+	}
+	response, err := client.Do(req)
+	if capture != nil {
+		capture.fillTLS(response)
+	}
+	if idle != nil {
+		response = idle.wrap(response, err)
+	}
+	if breaker != nil {
+		breaker.record(r.Request.URL.Host, err == nil && response.StatusCode < 500)
 	}
-	response, err := r.rawClient.Client.Do(r.Request)
 	if err != nil {
+		if choice != nil && choice.url != nil {
+			r.rawClient.proxyRotation.markFailed(choice.url)
+		}
 		r.rawClient.logger().Error("request failed", "error", err)
 		r.logRequest()
+		if r.rawClient.handleRequestErrorFunc != nil {
+			r.rawClient.handleRequestErrorFunc(r, err)
+		}
 		return nil, err
 	}
 	do := &Response{
 		rawRequest:      r,
 		Response:        response,
-		jsonUnmarshaler: json.Unmarshal,
+		jsonUnmarshaler: r.rawClient.jsonUnmarshal,
 		jsonMarshaler:   json.Marshal,
-		receivedAt:      time.Now(),
+		receivedAt:      now(),
+	}
+	if r.discardBody {
+		do.discard()
 	}
 	defer func() {
 		if do.rawRequest.rawClient.Debug {
@@ -280,6 +664,11 @@ func (r *Request) Do() (*Response, error) {
 			do.logResponse()
 		}
 	}()
+	for _, validator := range r.rawClient.responseValidators {
+		if err := validator(do); err != nil {
+			return do, &ValidationError{Err: err}
+		}
+	}
 	return do, nil
 }
 
@@ -302,13 +691,43 @@ func (c *Client) logger() LeveledLogger {
 
 // SetBodyJSON 将请求体设置为 JSON 对象
 func (c *Client) SetBodyJSON(data interface{}) *Client {
-	jsonString, err := marshalJSON(data)
+	jsonString, err := marshalJSON(data, c.jsonMarshal)
 	if err != nil {
 		c.logger().Error("failed to marshal JSON", "error", err)
 		return c
 	}
 	c.Body = jsonString
-	c.SetHeader("Content-Type", ContentTypeJson)
+	c.SetHeader("Content-Type", c.contentType(ContentTypeJson))
+	setAcceptIfAbsent(c.Header, ContentTypeJson)
+	return c
+}
+
+// SetJSONEscapeHTML 设置编码 JSON 请求体时是否转义 <、>、& 字符，默认转义
+func (c *Client) SetJSONEscapeHTML(escape bool) *Client {
+	if escape {
+		c.jsonMarshal = json.Marshal
+	} else {
+		c.jsonMarshal = marshalJSONNoEscapeHTML
+	}
+	return c
+}
+
+// SetStreamMode 设置流式模式，用于 SSE、大文件下载等长连接场景。
+// 启用后不再限制请求的总耗时，SetTimeout 设置的时间改为等待响应头以及每次读取响应体的超时，
+// 调试日志也不再读取响应体
+func (c *Client) SetStreamMode(enable bool) *Client {
+	c.StreamMode = enable
+	return c
+}
+
+// SetUseJSONNumber 设置解码 JSON 响应时是否将数字保留为 json.Number，
+// 启用后 ToMap、JSON 等方法不会丢失大整数的精度
+func (c *Client) SetUseJSONNumber(useNumber bool) *Client {
+	if useNumber {
+		c.jsonUnmarshal = unmarshalJSONUseNumber
+	} else {
+		c.jsonUnmarshal = json.Unmarshal
+	}
 	return c
 }
 
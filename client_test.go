@@ -0,0 +1,561 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// discardLogger 丢弃所有日志，避免测试输出被失败请求的日志淹没
+type discardLogger struct{}
+
+func (discardLogger) Error(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Warn(string, ...interface{})  {}
+
+func (l discardLogger) WithContext(context.Context) LeveledLogger { return l }
+
+// newTestClient 启动使用 handler 的测试服务器，返回以其地址为 BaseURL 的客户端
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient().SetBaseURL(srv.URL)
+	c.Logger = discardLogger{}
+	return c
+}
+
+func TestSetLogLevel(t *testing.T) {
+	c := NewClient()
+	var buf bytes.Buffer
+	c.logger().(*standardLogger).SetOutput(&buf)
+	c.SetLogLevel(LevelWarn)
+
+	logger := c.logger()
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	out := buf.String()
+	for _, msg := range []string{"debug message", "info message"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("output contains suppressed %q: %s", msg, out)
+		}
+	}
+	for _, msg := range []string{"warn message", "error message"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("output missing %q: %s", msg, out)
+		}
+	}
+}
+
+func TestSetDefaultCharset(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Content-Type"))
+	})
+	resp, err := c.R().SetMethod(http.MethodPost).SetBodyJSON(map[string]int{"a": 1}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != ContentTypeJson {
+		t.Errorf("without default charset Content-Type = %q, want %q", got, ContentTypeJson)
+	}
+
+	c.SetDefaultCharset("utf-8")
+	resp, err = c.R().SetMethod(http.MethodPost).SetBodyJSON(map[string]int{"a": 1}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.String(), "application/json; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	tests := map[string]string{
+		ContentTypeText:                     "text/plain; charset=utf-8",
+		"application/problem+json":          "application/problem+json; charset=utf-8",
+		ContentTypeStream:                   ContentTypeStream,
+		"image/png":                         "image/png",
+		"text/html; charset=gbk":            "text/html; charset=gbk",
+		"application/vnd.api+xml":           "application/vnd.api+xml; charset=utf-8",
+		"application/x-www-form-urlencoded": "application/x-www-form-urlencoded",
+	}
+	for mediaType, want := range tests {
+		if got := c.contentType(mediaType); got != want {
+			t.Errorf("contentType(%q) = %q, want %q", mediaType, got, want)
+		}
+	}
+}
+
+func TestSetBaseURLQueryParams(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RawQuery)
+	})
+	c.SetBaseURLQueryParam("api_key", "client-key").SetBaseURLQueryParams(map[string]string{"v": "2"})
+
+	tests := []struct {
+		name string
+		req  *Request
+		want url.Values
+	}{
+		{"client params only", c.R(), url.Values{"api_key": {"client-key"}, "v": {"2"}}},
+		{"request adds params", c.R().SetQueryParam("page", "3"), url.Values{"api_key": {"client-key"}, "v": {"2"}, "page": {"3"}}},
+		{"request overrides client", c.R().SetQueryParam("api_key", "request-key"), url.Values{"api_key": {"request-key"}, "v": {"2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.Execute("items")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := url.ParseQuery(resp.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Encode() != tt.want.Encode() {
+				t.Errorf("query = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripperFunc 将函数适配为 http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestSetHTTPClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/set", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	})
+	mux.HandleFunc("/cookie", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			io.WriteString(w, c.Value)
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/method", http.StatusFound)
+	})
+	mux.HandleFunc("/method", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method)
+	})
+
+	t.Run("custom transport", func(t *testing.T) {
+		c := newTestClient(t, mux.ServeHTTP)
+		var calls int32
+		c.SetHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return http.DefaultTransport.RoundTrip(req)
+		})})
+		if _, err := c.R().Execute("/method"); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("custom transport saw %d requests, want 1", n)
+		}
+	})
+
+	t.Run("keeps jar and transport", func(t *testing.T) {
+		c := newTestClient(t, mux.ServeHTTP)
+		var calls int32
+		base := c.Client.Transport
+		c.Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return base.RoundTrip(req)
+		})
+		if _, err := c.R().Execute("/set"); err != nil {
+			t.Fatal(err)
+		}
+		c.SetHTTPClient(&http.Client{})
+		resp, err := c.R().Execute("/cookie")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != "abc" {
+			t.Errorf("cookie after SetHTTPClient = %q, want jar kept", got)
+		}
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Errorf("wrapped transport saw %d requests, want 2", n)
+		}
+	})
+
+	t.Run("keeps redirect handling", func(t *testing.T) {
+		c := newTestClient(t, mux.ServeHTTP).SetPreserveMethodOnRedirect(true)
+		c.SetHTTPClient(&http.Client{})
+		resp, err := c.R().SetMethod(http.MethodPost).Execute("/redirect")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != http.MethodPost {
+			t.Errorf("method after redirect = %q, want POST preserved", got)
+		}
+	})
+
+	t.Run("adopts client CheckRedirect", func(t *testing.T) {
+		c := newTestClient(t, mux.ServeHTTP)
+		errStop := errors.New("no redirects")
+		hc := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return errStop }}
+		c.SetHTTPClient(hc).SetRetryMax(1)
+		if _, err := c.R().Execute("/redirect"); !errors.Is(err, errStop) {
+			t.Errorf("got error %v, want the client's CheckRedirect error", err)
+		}
+		if hc.Jar != nil || hc.Transport != nil {
+			t.Error("SetHTTPClient modified the caller's http.Client")
+		}
+	})
+
+	t.Run("nil ignored", func(t *testing.T) {
+		c := newTestClient(t, mux.ServeHTTP)
+		before := c.Client
+		if c.SetHTTPClient(nil).Client != before {
+			t.Error("SetHTTPClient(nil) replaced the client")
+		}
+	})
+}
+
+// captureLogger 记录所有日志消息及其参数，用于断言日志内容
+type captureLogger struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (l *captureLogger) log(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintln(&l.buf, append([]interface{}{msg}, args...)...)
+}
+
+func (l *captureLogger) Error(msg string, args ...interface{}) { l.log(msg, args...) }
+func (l *captureLogger) Info(msg string, args ...interface{})  { l.log(msg, args...) }
+func (l *captureLogger) Debug(msg string, args ...interface{}) { l.log(msg, args...) }
+func (l *captureLogger) Warn(msg string, args ...interface{})  { l.log(msg, args...) }
+
+func (l *captureLogger) WithContext(context.Context) LeveledLogger { return l }
+
+func (l *captureLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+func TestSetMaxBodyLogSize(t *testing.T) {
+	requestBody := strings.Repeat("q", 10000)
+	responseBody := strings.Repeat("r", 5000)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, responseBody)
+	}).SetDebug(true).SetMaxBodyLogSize(100)
+	logger := &captureLogger{}
+	c.Logger = logger
+
+	if _, err := c.R().SetMethod(http.MethodPost).SetBody(requestBody).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out := logger.String()
+	for _, want := range []string{
+		strings.Repeat("q", 100) + "...(truncated 9900 bytes)",
+		strings.Repeat("r", 100) + "...(truncated 4900 bytes)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q", want[95:])
+		}
+	}
+	if strings.Contains(out, strings.Repeat("q", 101)) || strings.Contains(out, strings.Repeat("r", 101)) {
+		t.Error("log output contains more than 100 bytes of a body")
+	}
+
+	logger = &captureLogger{}
+	c.SetMaxBodyLogSize(0).Logger = logger
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logger.String(), responseBody) {
+		t.Error("log output truncated the body with MaxBodyLogSize 0")
+	}
+}
+
+func TestSetSensitiveHeaders(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "set-cookie-secret"})
+		w.Header().Set("X-Api-Key", "response-key-secret")
+	}).SetDebug(true)
+	logger := &captureLogger{}
+	c.Logger = logger
+
+	_, err := c.R().
+		SetHeader("Authorization", "Bearer token-secret").
+		SetHeader("X-Api-Key", "request-key-secret").
+		SetHeader("X-Trace", "visible-trace").
+		SetCookie("sid=cookie-secret").
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := logger.String()
+	for _, secret := range []string{"token-secret", "cookie-secret", "set-cookie-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("default log output leaks %q", secret)
+		}
+	}
+	for _, visible := range []string{"request-key-secret", "visible-trace", redacted} {
+		if !strings.Contains(out, visible) {
+			t.Errorf("default log output missing %q", visible)
+		}
+	}
+
+	logger = &captureLogger{}
+	c.SetSensitiveHeaders("x-api-key").Logger = logger
+	if _, err := c.R().SetHeader("X-Api-Key", "request-key-secret").SetHeader("Authorization", "Bearer token-secret").Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out = logger.String()
+	for _, secret := range []string{"request-key-secret", "response-key-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output leaks %q after SetSensitiveHeaders", secret)
+		}
+	}
+	if !strings.Contains(out, "token-secret") {
+		t.Error("SetSensitiveHeaders did not replace the default list")
+	}
+}
+
+func TestSetMaxConcurrentRequests(t *testing.T) {
+	const limit, total = 3, 20
+	var inFlight, peak int32
+	entered := make(chan struct{}, total)
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		entered <- struct{}{}
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+	}).SetMaxConcurrentRequests(limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.R().Execute(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// 名额占满后才放行，峰值应恰好等于上限
+	for i := 0; i < limit; i++ {
+		<-entered
+	}
+	close(release)
+	wg.Wait()
+	if p := atomic.LoadInt32(&peak); p != limit {
+		t.Errorf("peak in-flight requests %d, want %d", p, limit)
+	}
+}
+
+func TestSetMaxConcurrentRequestsContext(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}).SetMaxConcurrentRequests(1).SetRetryMax(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := c.R().Execute(); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer func() {
+		close(release)
+		<-done
+	}()
+	// 等待第一个请求占用唯一的并发名额
+	<-entered
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.R().SetContext(ctx).Execute(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded while waiting for a slot", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("blocked request took %v to honour its context", elapsed)
+	}
+}
+
+func TestSetDefaultHeaders(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Accept"), r.Header.Get("X-Team"))
+	}).SetDefaultHeaders(map[string]string{"Accept": "application/json", "X-Team": "default"})
+
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"defaults apply", c.R(), "application/json|default"},
+		{"request overrides default", c.R().SetHeader("Accept", "text/csv"), "text/csv|default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("headers %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	c.SetHeaders(map[string]string{"X-Team": "forced"})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.String(), "application/json|forced"; got != want {
+		t.Errorf("SetHeaders did not take precedence over defaults: %q, want %q", got, want)
+	}
+}
+
+func TestSetMethodOverride(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Method, r.Header.Get("X-HTTP-Method-Override"))
+	}).SetMethodOverride(true)
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodPut, "POST|PUT"},
+		{http.MethodPatch, "POST|PATCH"},
+		{http.MethodDelete, "POST|DELETE"},
+		{http.MethodGet, "GET|"},
+		{http.MethodPost, "POST|"},
+	}
+	for _, tt := range tests {
+		resp, err := c.R().SetMethod(tt.method).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != tt.want {
+			t.Errorf("%s sent as %q, want %q", tt.method, got, tt.want)
+		}
+	}
+
+	resp, err := c.SetMethodOverride(false).R().SetMethod(http.MethodDelete).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "DELETE|" {
+		t.Errorf("DELETE with override disabled sent as %q, want DELETE|", got)
+	}
+}
+
+func TestAddResponseValidatorChain(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Content-Type", "text/plain")
+	})
+	errContentType := errors.New("unexpected content type")
+	var order []string
+	c.AddResponseValidator(func(r *Response) error {
+		order = append(order, "status")
+		if !r.IsSuccess() {
+			return errors.New("unexpected status")
+		}
+		return nil
+	}).AddResponseValidator(func(r *Response) error {
+		order = append(order, "content type")
+		if !r.IsContentType(ContentTypeJson) {
+			return errContentType
+		}
+		return nil
+	}).AddResponseValidator(func(r *Response) error {
+		order = append(order, "schema")
+		return nil
+	})
+
+	resp, err := c.R().Execute()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, errContentType) {
+		t.Fatalf("got error %v, want a *ValidationError wrapping %v", err, errContentType)
+	}
+	if resp == nil {
+		t.Error("response is nil on validation failure")
+	}
+	if want := []string{"status", "content type"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("validators ran %v, want %v", order, want)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("got %d attempts, want validation errors not retried", n)
+	}
+}
+
+func TestSetBaseURLFromEnv(t *testing.T) {
+	const key = "QUICKLYHTTPS_TEST_BASE_URL"
+	tests := []struct {
+		name     string
+		env      string
+		fallback []string
+		want     string
+		log      string
+	}{
+		{"from env", "https://staging.example.com/", nil, "https://staging.example.com", ""},
+		{"env wins over fallback", "https://staging.example.com", []string{"https://prod.example.com"}, "https://staging.example.com", ""},
+		{"fallback", "", []string{"https://prod.example.com"}, "https://prod.example.com", ""},
+		{"empty without fallback", "", nil, "https://original.example.com", "base URL environment variable is empty"},
+		{"invalid", "not a url", nil, "https://original.example.com", "invalid base URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(key, tt.env)
+			logger := &captureLogger{}
+			c := NewClient().SetBaseURL("https://original.example.com")
+			c.Logger = logger
+			c.SetBaseURLFromEnv(key, tt.fallback...)
+			if c.BaseURL != tt.want {
+				t.Errorf("BaseURL %q, want %q", c.BaseURL, tt.want)
+			}
+			if got := logger.String(); (tt.log == "") != (got == "") || !strings.Contains(got, tt.log) {
+				t.Errorf("log %q, want %q", got, tt.log)
+			}
+		})
+	}
+}
+
+func TestClientWhen(t *testing.T) {
+	for _, debug := range []bool{true, false} {
+		calls := 0
+		c := NewClient().When(debug, func(c *Client) {
+			calls++
+			c.SetHeader("X-Debug", "1")
+		})
+		wantCalls := 0
+		if debug {
+			wantCalls = 1
+		}
+		if got := c.Header.Get("X-Debug") == "1"; got != debug || calls != wantCalls {
+			t.Errorf("When(%v): header set %v after %d calls, want %d calls", debug, got, calls, wantCalls)
+		}
+	}
+}
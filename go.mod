@@ -1,14 +1,25 @@
 module github.com/catnovel/quicklyHttps
 
-go 1.19
+go 1.20
 
 require (
+	github.com/PuerkitoBio/goquery v1.9.2
 	github.com/tidwall/gjson v1.17.1
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
 	golang.org/x/net v0.25.0
+	golang.org/x/oauth2 v0.20.0
+	golang.org/x/sync v0.7.0
 	golang.org/x/text v0.15.0
+	google.golang.org/protobuf v1.34.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/andybalholm/cascadia v1.3.2 // indirect
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
+	go.opentelemetry.io/otel/metric v1.19.0 // indirect
 )
@@ -0,0 +1,221 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// maxHeaderBlockSize 累积的请求头超过该大小时不再调整顺序，直接原样写出
+const maxHeaderBlockSize = 1 << 20
+
+// headerOrder 记录请求头的发送顺序和设置前的拨号函数
+type headerOrder struct {
+	keys []string
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// SetHeaderOrder 设置请求头在报文中的发送顺序，未列出的头部保持原顺序排在其后，
+// 用于应对根据头部顺序识别客户端指纹的服务端。传入空切片取消设置。
+// 该设置通过改写 HTTP/1.1 连接上写出的报文实现，启用后 HTTPS 请求只会协商 HTTP/1.1；
+// 经由代理 CONNECT 隧道的 HTTPS 请求和分块传输请求之后同一连接上的请求不会调整顺序。
+// 由于 TLS 连接由客户端自行建立，响应的 TLS 信息在 Do 中根据实际使用的连接补全。
+// 需在 SetLocalAddr 等修改拨号函数的设置之后调用。
+func (c *Client) SetHeaderOrder(order []string) *Client {
+	t := c.transport()
+	if t == nil {
+		return c
+	}
+	if c.headerOrder == nil {
+		if len(order) == 0 {
+			return c
+		}
+		dial := t.DialContext
+		if dial == nil {
+			dial = newDialer(nil).DialContext
+		}
+		c.headerOrder = &headerOrder{dial: dial}
+	}
+	if len(order) == 0 {
+		t.DialContext = c.headerOrder.dial
+		t.DialTLSContext = nil
+		c.headerOrder = nil
+		return c
+	}
+	keys := make([]string, len(order))
+	for i, key := range order {
+		keys[i] = textproto.CanonicalMIMEHeaderKey(key)
+	}
+	dial := c.headerOrder.dial
+	c.headerOrder.keys = keys
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &orderedConn{Conn: conn, keys: keys}, nil
+	}
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		config := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			config = t.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			config.ServerName = host
+		}
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &orderedConn{Conn: tlsConn, keys: keys}, nil
+	}
+	return c
+}
+
+// orderedConn 在写出请求时按指定顺序重排请求头，请求体原样写出
+type orderedConn struct {
+	net.Conn
+	keys        []string
+	head        []byte // 尚未写出的请求头
+	remaining   int64  // 当前请求体未写出的字节数
+	passthrough bool   // 之后的数据无法识别请求边界，原样写出
+}
+
+// ConnectionState 返回底层 TLS 连接的状态，非 TLS 连接返回零值。
+// 较新版本的 http.Transport 通过该方法为自定义 TLS 拨号建立的连接填充 Response.TLS
+func (c *orderedConn) ConnectionState() tls.ConnectionState {
+	if tlsConn, ok := c.Conn.(*tls.Conn); ok {
+		return tlsConn.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+// connCapture 记录请求实际使用的连接
+type connCapture struct {
+	conn net.Conn
+}
+
+// withConnCapture 通过 httptrace 记录请求使用的连接，发生重定向时记录最后一次请求的连接
+func withConnCapture(req *http.Request) (*http.Request, *connCapture) {
+	capture := &connCapture{}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			capture.conn = info.Conn
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), capture
+}
+
+// fillTLS 为经由 orderedConn 的 HTTPS 响应补全 TLS 信息，
+// 较早版本的 http.Transport 只在自定义 TLS 拨号返回 *tls.Conn 时才会填充 Response.TLS
+func (c *connCapture) fillTLS(response *http.Response) {
+	if response == nil || response.TLS != nil {
+		return
+	}
+	conn, ok := c.conn.(*orderedConn)
+	if !ok {
+		return
+	}
+	if state := conn.ConnectionState(); state.HandshakeComplete {
+		response.TLS = &state
+	}
+}
+
+func (c *orderedConn) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if c.passthrough {
+			if _, err := c.Conn.Write(p); err != nil {
+				return 0, err
+			}
+			break
+		}
+		if c.remaining > 0 {
+			k := len(p)
+			if int64(k) > c.remaining {
+				k = int(c.remaining)
+			}
+			if _, err := c.Conn.Write(p[:k]); err != nil {
+				return 0, err
+			}
+			c.remaining -= int64(k)
+			p = p[k:]
+			continue
+		}
+		c.head = append(c.head, p...)
+		p = nil
+		end := bytes.Index(c.head, []byte("\r\n\r\n"))
+		if end < 0 {
+			if len(c.head) > maxHeaderBlockSize {
+				c.passthrough = true
+				p, c.head = c.head, nil
+			}
+			continue
+		}
+		head := c.reorder(c.head[:end])
+		p = append([]byte(nil), c.head[end+4:]...)
+		c.head = nil
+		if _, err := c.Conn.Write(head); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// reorder 重排请求头并根据请求头确定请求体的长度
+func (c *orderedConn) reorder(head []byte) []byte {
+	lines := strings.Split(string(head), "\r\n")
+	requestLine, fields := lines[0], lines[1:]
+	if strings.HasPrefix(requestLine, "CONNECT ") {
+		c.passthrough = true
+	}
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		name, value, _ := strings.Cut(field, ":")
+		names[i] = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		switch names[i] {
+		case "Content-Length":
+			c.remaining, _ = strconv.ParseInt(value, 10, 64)
+		case "Transfer-Encoding", "Upgrade":
+			c.passthrough = true
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(requestLine)
+	buf.WriteString("\r\n")
+	written := make([]bool, len(fields))
+	for _, key := range c.keys {
+		for i, name := range names {
+			if !written[i] && name == key {
+				buf.WriteString(fields[i])
+				buf.WriteString("\r\n")
+				written[i] = true
+			}
+		}
+	}
+	for i, field := range fields {
+		if !written[i] {
+			buf.WriteString(field)
+			buf.WriteString("\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,182 @@
+package quicklyHttps
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rawRequest 是原始报文中按发送顺序记录的请求头和请求体
+type rawRequest struct {
+	names []string
+	body  string
+}
+
+// newRawServer 启动直接读取 TCP 报文的 HTTP/1.1 服务器，记录每个请求的头部顺序，
+// 以请求体作为响应体返回，支持同一连接上的多个请求
+func newRawServer(t *testing.T) (string, func() []rawRequest) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var requests []rawRequest
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		for {
+			if _, err := reader.ReadLine(); err != nil {
+				return
+			}
+			var req rawRequest
+			var length int
+			for {
+				line, err := reader.ReadLine()
+				if err != nil {
+					return
+				}
+				if line == "" {
+					break
+				}
+				name, value, _ := strings.Cut(line, ":")
+				req.names = append(req.names, name)
+				if textproto.CanonicalMIMEHeaderKey(name) == "Content-Length" {
+					length, _ = strconv.Atoi(strings.TrimSpace(value))
+				}
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(reader.R, body); err != nil {
+				return
+			}
+			req.body = string(body)
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return "http://" + ln.Addr().String(), func() []rawRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]rawRequest(nil), requests...)
+	}
+}
+
+func TestSetHeaderOrder(t *testing.T) {
+	addr, requests := newRawServer(t)
+	c := NewClient().SetBaseURL(addr).
+		SetHeaderOrder([]string{"x-third", "User-Agent", "X-First", "content-length", "Host"})
+	c.Logger = discardLogger{}
+
+	for _, body := range []string{"", "first body", "second body on the same connection"} {
+		req := c.R().SetHeader("X-First", "1").SetHeader("X-Third", "3")
+		if body != "" {
+			req.SetMethod(http.MethodPost).SetBody(body)
+		}
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != body {
+			t.Errorf("echoed body %q, want %q", got, body)
+		}
+	}
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("server received %d requests, want 3", len(got))
+	}
+	for i, req := range got {
+		var ordered []string
+		for _, name := range req.names {
+			switch canonical := textproto.CanonicalMIMEHeaderKey(name); canonical {
+			case "X-Third", "User-Agent", "X-First", "Content-Length", "Host":
+				ordered = append(ordered, canonical)
+			}
+		}
+		want := []string{"X-Third", "User-Agent", "X-First", "Content-Length", "Host"}
+		if i == 0 {
+			want = []string{"X-Third", "User-Agent", "X-First", "Host"}
+		}
+		if !reflect.DeepEqual(ordered, want) {
+			t.Errorf("request %d header order %v, want %v (all headers %v)", i, ordered, want, req.names)
+		}
+	}
+}
+
+func TestSetHeaderOrderTLSState(t *testing.T) {
+	c := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}).SetHeaderOrder([]string{"User-Agent", "Host"})
+
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "HTTP/1.1" {
+		t.Errorf("server saw %s, want HTTP/1.1 when a header order is set", got)
+	}
+	state := resp.Raw().TLS
+	if state == nil {
+		t.Fatal("Response.TLS is nil with a header order set")
+	}
+	if !state.HandshakeComplete || state.Version < tls.VersionTLS12 {
+		t.Errorf("unexpected TLS state: handshake complete %v, version %x", state.HandshakeComplete, state.Version)
+	}
+	if len(state.PeerCertificates) == 0 {
+		t.Error("TLS state has no peer certificates")
+	}
+
+	c.SetHeaderOrder(nil)
+	resp, err = c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Raw().TLS == nil {
+		t.Error("Response.TLS is nil after clearing the header order")
+	}
+}
+
+func TestConnCaptureFillTLS(t *testing.T) {
+	c := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.SetHeaderOrder([]string{"Host"})
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, capture := withConnCapture(req)
+	response, err := c.Client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if _, ok := capture.conn.(*orderedConn); !ok {
+		t.Fatalf("captured connection %T, want *orderedConn", capture.conn)
+	}
+	// 模拟不识别 orderedConn 的 http.Transport
+	response.TLS = nil
+	capture.fillTLS(response)
+	if response.TLS == nil || !response.TLS.HandshakeComplete {
+		t.Errorf("fillTLS did not restore the TLS state: %+v", response.TLS)
+	}
+}
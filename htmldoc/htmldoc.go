@@ -0,0 +1,20 @@
+// Package htmldoc 为 quicklyHttps 提供基于 goquery 的 HTML 解析，便于使用 CSS 选择器提取页面内容。
+package htmldoc
+
+import (
+	"bytes"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/catnovel/quicklyHttps"
+)
+
+// HTMLDocument 将响应体转换为 UTF-8 后解析为 goquery 文档，字符集的识别规则与 Response.DetectEncoding 相同
+func HTMLDocument(resp *quicklyHttps.Response) (*goquery.Document, error) {
+	if err := resp.DetectEncoding(); err != nil {
+		return nil, err
+	}
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	return goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+}
@@ -0,0 +1,97 @@
+package htmldoc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/catnovel/quicklyHttps"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const samplePage = `<html><head><title>目录</title></head><body>
+<ul id="chapters">
+  <li><a href="/1.html">第一章</a></li>
+  <li><a href="/2.html">第二章</a></li>
+  <li class="vip"><a href="/3.html">第三章</a></li>
+</ul></body></html>`
+
+// fetchDocument 以指定的 Content-Type 和响应体请求测试服务器并解析为文档
+func fetchDocument(t *testing.T, contentType string, body []byte) *goquery.Document {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	resp, err := quicklyHttps.NewClient().SetBaseURL(srv.URL).R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := HTMLDocument(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// chapters 返回目录中的章节标题和链接
+func chapters(doc *goquery.Document) ([]string, []string) {
+	var titles, links []string
+	doc.Find("#chapters li a").Each(func(_ int, s *goquery.Selection) {
+		titles = append(titles, s.Text())
+		links = append(links, s.AttrOr("href", ""))
+	})
+	return titles, links
+}
+
+func TestHTMLDocument(t *testing.T) {
+	doc := fetchDocument(t, "text/html; charset=utf-8", []byte(samplePage))
+	titles, links := chapters(doc)
+	if want := []string{"第一章", "第二章", "第三章"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles %v, want %v", titles, want)
+	}
+	if want := []string{"/1.html", "/2.html", "/3.html"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("links %v, want %v", links, want)
+	}
+	if got := doc.Find("li.vip a").Text(); got != "第三章" {
+		t.Errorf("li.vip text %q, want 第三章", got)
+	}
+	if got := doc.Find("title").Text(); got != "目录" {
+		t.Errorf("title %q, want 目录", got)
+	}
+}
+
+func TestHTMLDocumentGBK(t *testing.T) {
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(samplePage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := fetchDocument(t, "text/html; charset=gbk", []byte(encoded))
+	titles, _ := chapters(doc)
+	if want := []string{"第一章", "第二章", "第三章"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles %v decoded from GBK, want %v", titles, want)
+	}
+}
+
+func TestHTMLDocumentReadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, samplePage)
+	}))
+	t.Cleanup(srv.Close)
+	resp, err := quicklyHttps.NewClient().SetBaseURL(srv.URL).R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := resp.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if _, err := HTMLDocument(resp); err == nil {
+		t.Error("HTMLDocument of a consumed body returned no error")
+	}
+}
@@ -0,0 +1,138 @@
+package quicklyHttps
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// multipartFile 描述 multipart 请求中的一个文件字段
+type multipartFile struct {
+	field       string
+	fileName    string
+	contentType string
+	path        string
+	reader      io.Reader
+}
+
+// open 打开文件内容，本地文件每次重新打开，可 Seek 的 Reader 重置到开头
+func (f *multipartFile) open() (io.ReadCloser, error) {
+	if f.path != "" {
+		return os.Open(f.path)
+	}
+	if seeker, ok := f.reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(f.reader), nil
+}
+
+// replayable 判断文件内容是否可以在重试时重新读取
+func (f *multipartFile) replayable() bool {
+	if f.path != "" {
+		return true
+	}
+	_, ok := f.reader.(io.Seeker)
+	return ok
+}
+
+// writeTo 将文件内容作为一个 part 写入 multipart.Writer
+func (f *multipartFile) writeTo(mw *multipart.Writer) error {
+	content, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	contentType := f.contentType
+	if contentType == "" {
+		contentType = ContentTypeStream
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(f.field), quoteEscaper.Replace(f.fileName)))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, content)
+	return err
+}
+
+// SetFile 添加一个从本地文件读取的文件字段，上传时以流的方式读取文件
+func (r *Request) SetFile(field, filePath string) *Request {
+	r.files = append(r.files, &multipartFile{field: field, fileName: filepath.Base(filePath), path: filePath})
+	return r
+}
+
+// SetFileReader 添加一个从 Reader 读取的文件字段，
+// Reader 不支持 Seek 时请求体无法在重试时重放
+func (r *Request) SetFileReader(field, fileName string, reader io.Reader) *Request {
+	r.files = append(r.files, &multipartFile{field: field, fileName: fileName, reader: reader})
+	return r
+}
+
+// SetFileFieldWithType 添加一个从 Reader 读取的文件字段，并指定该 part 的 Content-Type，如 image/png，
+// Reader 不支持 Seek 时请求体无法在重试时重放
+func (r *Request) SetFileFieldWithType(field, fileName, contentType string, reader io.Reader) *Request {
+	r.files = append(r.files, &multipartFile{field: field, fileName: fileName, contentType: contentType, reader: reader})
+	return r
+}
+
+// SetMultipartBoundary 设置固定的 multipart 分隔符，便于签名和测试，默认使用随机分隔符。
+// 分隔符需符合 RFC 2046：1 到 70 个字符，只包含字母、数字和 '()+_,-./:=? 以及不在末尾的空格
+func (r *Request) SetMultipartBoundary(boundary string) *Request {
+	if err := multipart.NewWriter(io.Discard).SetBoundary(boundary); err != nil {
+		r.rawClient.logger().Error("invalid multipart boundary", "boundary", boundary, "error", err)
+		return r
+	}
+	r.boundary = boundary
+	return r
+}
+
+// multipartReplayable 判断 multipart 请求体是否可以在重试时重新生成
+func (r *Request) multipartReplayable() bool {
+	for _, f := range r.files {
+		if !f.replayable() {
+			return false
+		}
+	}
+	return true
+}
+
+// multipartBody 返回通过 io.Pipe 流式生成的 multipart 请求体，不会将文件整体读入内存
+func (r *Request) multipartBody(boundary string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(r.writeMultipart(pw, boundary))
+	}()
+	return pr
+}
+
+// writeMultipart 依次写入表单参数和文件字段
+func (r *Request) writeMultipart(w io.Writer, boundary string) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for key, values := range r.formParams {
+		for _, value := range values {
+			if err := mw.WriteField(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range r.files {
+		if err := f.writeTo(mw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
@@ -0,0 +1,184 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// patternReader 生成 n 字节的数据而不在内存中保留，且不支持 Seek
+type patternReader struct{ n int64 }
+
+func (p *patternReader) Read(b []byte) (int, error) {
+	if p.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > p.n {
+		b = b[:p.n]
+	}
+	for i := range b {
+		b[i] = 'x'
+	}
+	p.n -= int64(len(b))
+	return len(b), nil
+}
+
+func TestMultipartStreamingBoundedMemory(t *testing.T) {
+	const size = 64 << 20
+	var received int64
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FileName() == "big.bin" {
+				received, _ = io.Copy(io.Discard, part)
+			}
+		}
+	})
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	resp, err := c.R().SetFileReader("file", "big.bin", &patternReader{n: size}).SetMethod(http.MethodPost).Execute("/upload")
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSuccess() {
+		t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+	}
+	if received != size {
+		t.Errorf("server received %d bytes, want %d", received, size)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+		t.Errorf("upload of %d bytes allocated %d bytes, want the body streamed", size, allocated)
+	}
+}
+
+func TestMultipartContentMD5(t *testing.T) {
+	const content = "file content for the checksum"
+	var file []byte
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := checkContentMD5(w, r)
+		if !ok {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _ = io.ReadAll(f)
+	})
+
+	t.Run("seekable reader", func(t *testing.T) {
+		resp, err := c.R().SetContentMD5().
+			SetFileReader("file", "a.txt", strings.NewReader(content)).SetMethod(http.MethodPost).Execute("/upload")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSuccess() {
+			t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+		}
+		if string(file) != content {
+			t.Errorf("server got file %q, want %q", file, content)
+		}
+	})
+
+	t.Run("non-seekable reader", func(t *testing.T) {
+		_, err := c.R().SetContentMD5().
+			SetFileReader("file", "a.txt", io.MultiReader(strings.NewReader(content))).SetMethod(http.MethodPost).Execute("/upload")
+		if err == nil || !strings.Contains(err.Error(), "replayable") {
+			t.Errorf("got error %v, want Content-MD5 replayable body error", err)
+		}
+	})
+}
+
+func TestSetMultipartBoundary(t *testing.T) {
+	const boundary = "fixed-boundary_0123'()+,./:=?"
+	var contentType, body string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		contentType, body = r.Header.Get("Content-Type"), string(data)
+	})
+	_, err := c.R().SetMultipartBoundary(boundary).
+		SetFileReader("file", "a.txt", strings.NewReader("hello")).
+		SetMethod(http.MethodPost).Execute("/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["boundary"] != boundary {
+		t.Errorf("Content-Type boundary %q, want %q", params["boundary"], boundary)
+	}
+	if !strings.Contains(body, "--"+boundary+"\r\n") || !strings.Contains(body, "--"+boundary+"--") {
+		t.Errorf("body does not use boundary %q:\n%s", boundary, body)
+	}
+}
+
+func TestSetMultipartBoundaryInvalid(t *testing.T) {
+	c := NewClient()
+	logger := &captureLogger{}
+	c.Logger = logger
+	for _, boundary := range []string{"", "trailing space ", "bad\"quote", strings.Repeat("a", 71)} {
+		if req := c.R().SetMultipartBoundary(boundary); req.boundary != "" {
+			t.Errorf("SetMultipartBoundary(%q) accepted an invalid boundary", boundary)
+		}
+	}
+	if !strings.Contains(logger.String(), "invalid multipart boundary") {
+		t.Errorf("invalid boundaries were not logged: %s", logger.String())
+	}
+	if got := c.R().SetMultipartBoundary("ok").boundary; got != "ok" {
+		t.Errorf("boundary %q, want ok", got)
+	}
+}
+
+func TestSetFileFieldWithType(t *testing.T) {
+	types := make(map[string]string)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			types[part.FileName()] = part.Header.Get("Content-Type")
+		}
+	})
+	resp, err := c.R().
+		SetFileFieldWithType("avatar", "me.png", "image/png", strings.NewReader("\x89PNG")).
+		SetFileFieldWithType("doc", "a.json", "application/json", strings.NewReader("{}")).
+		SetFileReader("raw", "blob.bin", strings.NewReader("raw")).
+		SetMethod(http.MethodPost).Execute("/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSuccess() {
+		t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+	}
+	want := map[string]string{"me.png": "image/png", "a.json": "application/json", "blob.bin": "application/octet-stream"}
+	for name, contentType := range want {
+		if types[name] != contentType {
+			t.Errorf("part %s Content-Type %q, want %q", name, types[name], contentType)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package oauth 为 quicklyHttps 提供 OAuth2 集成，每次请求前从 TokenSource 获取令牌并设置 Authorization 请求头。
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/catnovel/quicklyHttps"
+	"golang.org/x/oauth2"
+)
+
+// SetOAuth2TokenSource 设置 OAuth2 令牌来源，每次请求（包括重试）前获取令牌并设置 Authorization: Bearer 请求头，
+// 令牌会被缓存并在过期前自动刷新。重定向到其他主机时不会携带令牌，传入 nil 取消设置。
+func SetOAuth2TokenSource(c *quicklyHttps.Client, ts oauth2.TokenSource) *quicklyHttps.Client {
+	current, wrapped := c.Client.Transport.(*transport)
+	switch {
+	case ts != nil && wrapped:
+		current.source = oauth2.ReuseTokenSource(nil, ts)
+	case ts != nil:
+		c.Client.Transport = &transport{base: c.Client.Transport, source: oauth2.ReuseTokenSource(nil, ts)}
+	case wrapped:
+		c.Client.Transport = current.base
+	}
+	return c
+}
+
+// transport 在发送请求前设置 OAuth2 令牌的 RoundTripper
+type transport struct {
+	base   http.RoundTripper
+	source oauth2.TokenSource
+}
+
+// Unwrap 返回被包装的 RoundTripper，使客户端的传输层设置仍然生效
+func (t *transport) Unwrap() http.RoundTripper {
+	return t.base
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Response != nil && req.Response.Request.URL.Host != req.URL.Host {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.source.Token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	token.SetAuthHeader(req)
+	return t.base.RoundTrip(req)
+}
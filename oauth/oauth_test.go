@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/catnovel/quicklyHttps"
+	"golang.org/x/oauth2"
+)
+
+// rotatingSource 每次调用返回新的令牌，lifetime 为令牌的有效期
+type rotatingSource struct {
+	calls    int32
+	lifetime time.Duration
+}
+
+func (s *rotatingSource) Token() (*oauth2.Token, error) {
+	n := atomic.AddInt32(&s.calls, 1)
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("token-%d", n),
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(s.lifetime),
+	}, nil
+}
+
+// newAuthServer 启动以响应体返回 Authorization 请求头的测试服务器
+func newAuthServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSetOAuth2TokenSourceRotates(t *testing.T) {
+	srv := newAuthServer(t)
+	// 有效期短于 oauth2 的提前刷新时间，每次请求都会获取新令牌
+	source := &rotatingSource{lifetime: time.Second}
+	c := SetOAuth2TokenSource(quicklyHttps.NewClient().SetBaseURL(srv.URL), source)
+	for i := 1; i <= 3; i++ {
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.String(), fmt.Sprintf("Bearer token-%d", i); got != want {
+			t.Errorf("request %d sent Authorization %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSetOAuth2TokenSourceCaches(t *testing.T) {
+	srv := newAuthServer(t)
+	source := &rotatingSource{lifetime: time.Hour}
+	c := SetOAuth2TokenSource(quicklyHttps.NewClient().SetBaseURL(srv.URL), source)
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != "Bearer token-1" {
+			t.Errorf("Authorization %q, want the cached token", got)
+		}
+	}
+	if n := atomic.LoadInt32(&source.calls); n != 1 {
+		t.Errorf("token source called %d times, want 1", n)
+	}
+
+	SetOAuth2TokenSource(c, nil)
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "" {
+		t.Errorf("Authorization %q after clearing the token source, want none", got)
+	}
+}
+
+func TestSetOAuth2TokenSourceCrossHostRedirect(t *testing.T) {
+	other := newAuthServer(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+	c := SetOAuth2TokenSource(quicklyHttps.NewClient().SetBaseURL(srv.URL), &rotatingSource{lifetime: time.Hour})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "" {
+		t.Errorf("token %q leaked to another host on redirect", got)
+	}
+}
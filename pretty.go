@@ -0,0 +1,141 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+)
+
+// voidElements 没有结束标签的 HTML 元素
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// indentXML 重新缩进 XML 文档，只包含文本的元素保持在同一行，命名空间前缀保持原样
+func indentXML(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var buf strings.Builder
+	depth := 0
+	// open 尚未闭合的元素，用于发现不匹配的结束标签
+	var open []string
+	// afterStart 表示上一个写入的是开始标签，afterText 表示开始标签后紧跟着写入了文本
+	afterStart, afterText := false, false
+	newline := func() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat("  ", depth))
+	}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			open = append(open, xmlName(t.Name))
+			newline()
+			buf.WriteString("<" + xmlName(t.Name))
+			for _, attr := range t.Attr {
+				buf.WriteString(" " + xmlName(attr.Name) + `="`)
+				_ = xml.EscapeText(&buf, []byte(attr.Value))
+				buf.WriteString(`"`)
+			}
+			buf.WriteString(">")
+			depth++
+			afterStart, afterText = true, false
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != xmlName(t.Name) {
+				return "", fmt.Errorf("unexpected end element </%s>", xmlName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if depth > 0 {
+				depth--
+			}
+			if !afterStart && !afterText {
+				newline()
+			}
+			buf.WriteString("</" + xmlName(t.Name) + ">")
+			afterStart, afterText = false, false
+		case xml.CharData:
+			text := bytes.TrimSpace(t)
+			if len(text) == 0 {
+				continue
+			}
+			if !afterStart {
+				newline()
+			}
+			_ = xml.EscapeText(&buf, text)
+			afterText, afterStart = afterStart, false
+		case xml.Comment:
+			newline()
+			buf.WriteString("<!--" + string(t) + "-->")
+			afterStart, afterText = false, false
+		case xml.ProcInst:
+			newline()
+			buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+			afterStart, afterText = false, false
+		case xml.Directive:
+			newline()
+			buf.WriteString("<!" + string(t) + ">")
+			afterStart, afterText = false, false
+		}
+	}
+	if len(open) > 0 {
+		return "", fmt.Errorf("unclosed element <%s>", open[len(open)-1])
+	}
+	return buf.String(), nil
+}
+
+// xmlName 返回带命名空间前缀的 XML 名称
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// indentHTML 按标签层级缩进 HTML 文档，文本内容会去除首尾空白
+func indentHTML(body []byte) (string, error) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	var buf strings.Builder
+	depth := 0
+	writeLine := func(raw []byte) {
+		buf.WriteString(strings.Repeat("  ", depth))
+		buf.Write(raw)
+		buf.WriteByte('\n')
+	}
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				return strings.TrimSuffix(buf.String(), "\n"), nil
+			}
+			return "", tokenizer.Err()
+		case html.TextToken:
+			if text := bytes.TrimSpace(tokenizer.Raw()); len(text) > 0 {
+				writeLine(text)
+			}
+		case html.StartTagToken:
+			writeLine(tokenizer.Raw())
+			name, _ := tokenizer.TagName()
+			if !voidElements[string(name)] {
+				depth++
+			}
+		case html.EndTagToken:
+			if depth > 0 {
+				depth--
+			}
+			writeLine(tokenizer.Raw())
+		default:
+			writeLine(bytes.TrimSpace(tokenizer.Raw()))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package quicklyHttps
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestResponsePrettyPrint(t *testing.T) {
+	tests := []struct {
+		name, contentType, body, want string
+	}{
+		{"json", "application/json", `{"a":1,"b":[true]}`, "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}"},
+		{"xml", "application/xml", `<?xml version="1.0"?><root><item id="1">one</item><empty></empty></root>`,
+			"<?xml version=\"1.0\"?>\n<root>\n  <item id=\"1\">one</item>\n  <empty></empty>\n</root>"},
+		{"atom+xml", "application/atom+xml; charset=utf-8", `<feed><title>t</title></feed>`,
+			"<feed>\n  <title>t</title>\n</feed>"},
+		{"html", "text/html; charset=utf-8", `<html><body><p>hi</p><br></body></html>`,
+			"<html>\n  <body>\n    <p>\n      hi\n    </p>\n    <br>\n  </body>\n</html>"},
+		{"unknown type falls back", "text/plain", "plain text body", "plain text body"},
+		{"mismatched xml falls back", "application/xml", "<root><unclosed></root>", "<root><unclosed></root>"},
+		{"unclosed xml falls back", "application/xml", "<root><item>x</item>", "<root><item>x</item>"},
+		{"namespace prefix kept", "text/xml", `<soap:Envelope xmlns:soap="urn:s"><soap:Body/></soap:Envelope>`,
+			"<soap:Envelope xmlns:soap=\"urn:s\">\n  <soap:Body></soap:Body>\n</soap:Envelope>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				io.WriteString(w, tt.body)
+			})
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.PrettyPrint(); got != tt.want {
+				t.Errorf("PrettyPrint() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package protobuf 为 quicklyHttps 提供 protobuf 请求体与响应体的编解码。
+package protobuf
+
+import (
+	"errors"
+
+	"github.com/catnovel/quicklyHttps"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentTypeProtobuf protobuf 请求体的 Content-Type
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// SetBody 将 protobuf 消息编码为请求体并设置 Content-Type: application/x-protobuf
+// msg 为 nil 时记录错误并保持请求体不变
+func SetBody(r *quicklyHttps.Request, msg proto.Message) *quicklyHttps.Request {
+	return r.SetBodyMarshal(msg, func(interface{}) ([]byte, error) {
+		if msg == nil {
+			return nil, errors.New("nil protobuf message")
+		}
+		return proto.Marshal(msg)
+	}, ContentTypeProtobuf)
+}
+
+// Unmarshal 将响应体解码为 protobuf 消息
+func Unmarshal(resp *quicklyHttps.Response, msg proto.Message) error {
+	body := resp.Body()
+	if resp.Err != nil {
+		return resp.Err
+	}
+	return proto.Unmarshal(body, msg)
+}
@@ -0,0 +1,108 @@
+package protobuf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/catnovel/quicklyHttps"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != ContentTypeProtobuf {
+			http.Error(w, "unexpected Content-Type "+got, http.StatusUnsupportedMediaType)
+			return
+		}
+		// 原样返回请求体，客户端解码后应得到相同的消息
+		w.Header().Set("Content-Type", ContentTypeProtobuf)
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	msg, err := structpb.NewStruct(map[string]interface{}{
+		"name":  "quicklyHttps",
+		"count": 3,
+		"tags":  []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := quicklyHttps.NewClient().SetBaseURL(srv.URL).R().SetMethod(http.MethodPost)
+	resp, err := SetBody(req, msg).Execute("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+	}
+	got := &structpb.Struct{}
+	if err := Unmarshal(resp, got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, msg) {
+		t.Errorf("round trip got %v, want %v", got, msg)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0xff, 0xff, 0xff})
+	}))
+	defer srv.Close()
+
+	resp, err := quicklyHttps.NewClient().SetBaseURL(srv.URL).R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(resp, &structpb.Struct{}); err == nil {
+		t.Error("expected error decoding invalid protobuf")
+	}
+}
+
+// recordingLogger 记录错误日志的消息
+type recordingLogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *recordingLogger) Error(msg string, _ ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, msg)
+}
+func (l *recordingLogger) Info(string, ...interface{})  {}
+func (l *recordingLogger) Debug(string, ...interface{}) {}
+func (l *recordingLogger) Warn(string, ...interface{})  {}
+
+func (l *recordingLogger) WithContext(context.Context) quicklyHttps.LeveledLogger { return l }
+
+func TestSetBodyNilMessage(t *testing.T) {
+	var contentType string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	logger := &recordingLogger{}
+	c := quicklyHttps.NewClient().SetBaseURL(srv.URL)
+	c.Logger = logger
+	if _, err := SetBody(c.R().SetMethod(http.MethodPost), nil).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if contentType == ContentTypeProtobuf || len(body) != 0 {
+		t.Errorf("nil message sent Content-Type %q with %d body bytes", contentType, len(body))
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if len(logger.errors) == 0 {
+		t.Error("nil message was not logged as an error")
+	}
+}
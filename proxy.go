@@ -0,0 +1,124 @@
+package quicklyHttps
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	urlpkg "net/url"
+	"sync"
+	"time"
+)
+
+// proxyCooldown 代理请求失败后被标记为不可用的时长
+const proxyCooldown = 30 * time.Second
+
+type proxyChoiceKey struct{}
+
+// proxyChoice 记录一次请求实际使用的代理，用于失败时标记该代理
+type proxyChoice struct {
+	url *urlpkg.URL
+}
+
+// rotatingProxy 代理池中的单个代理
+type rotatingProxy struct {
+	url            *urlpkg.URL
+	unhealthyUntil time.Time
+}
+
+// proxyRotation 以轮询方式从代理池中选择代理，跳过暂时不可用的代理
+type proxyRotation struct {
+	mu      sync.Mutex
+	proxies []*rotatingProxy
+	next    int
+}
+
+// proxy 实现 http.Transport.Proxy，为每个请求选择下一个可用的代理，
+// 全部代理都不可用时选择最早恢复的代理
+func (p *proxyRotation) proxy(req *http.Request) (*urlpkg.URL, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := now()
+	var chosen *rotatingProxy
+	for i := 0; i < len(p.proxies); i++ {
+		candidate := p.proxies[(p.next+i)%len(p.proxies)]
+		if !current.Before(candidate.unhealthyUntil) {
+			chosen = candidate
+			p.next = (p.next + i + 1) % len(p.proxies)
+			break
+		}
+	}
+	if chosen == nil {
+		for _, candidate := range p.proxies {
+			if chosen == nil || candidate.unhealthyUntil.Before(chosen.unhealthyUntil) {
+				chosen = candidate
+			}
+		}
+	}
+	if choice, ok := req.Context().Value(proxyChoiceKey{}).(*proxyChoice); ok {
+		choice.url = chosen.url
+	}
+	return chosen.url, nil
+}
+
+// markFailed 将代理标记为暂时不可用
+func (p *proxyRotation) markFailed(u *urlpkg.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, proxy := range p.proxies {
+		if proxy.url == u {
+			proxy.unhealthyUntil = now().Add(proxyCooldown)
+		}
+	}
+}
+
+// withProxyChoice 在请求上下文中放入代理记录，以便请求失败后知道使用了哪个代理
+func withProxyChoice(req *http.Request) (*http.Request, *proxyChoice) {
+	choice := &proxyChoice{}
+	return req.WithContext(context.WithValue(req.Context(), proxyChoiceKey{}, choice)), choice
+}
+
+// SetProxyRotation 设置代理池，每个请求按轮询方式选择代理，
+// 请求失败的代理会被暂时跳过，冷却后重新启用
+func (c *Client) SetProxyRotation(proxies []string) *Client {
+	rotation := &proxyRotation{}
+	for _, proxy := range proxies {
+		proxyURL, err := urlpkg.Parse(proxy)
+		if err != nil {
+			c.logger().Error("invalid proxy URL", "error", err)
+			continue
+		}
+		rotation.proxies = append(rotation.proxies, &rotatingProxy{url: proxyURL})
+	}
+	if len(rotation.proxies) == 0 {
+		c.logger().Error("no valid proxy URL, setting ignored")
+		return c
+	}
+	if t := c.transport(); t != nil {
+		t.Proxy = rotation.proxy
+		c.proxyRotation = rotation
+	}
+	return c
+}
+
+// SetProxyConnectHeader 设置代理 CONNECT 请求携带的头部，如认证令牌或路由信息，
+// 替换之前设置的所有头部（包括 SetProxyBasicAuth 设置的认证头部），
+// 只作用于经代理隧道的 HTTPS 请求
+func (c *Client) SetProxyConnectHeader(header http.Header) *Client {
+	if t := c.transport(); t != nil {
+		t.ProxyConnectHeader = header.Clone()
+	}
+	return c
+}
+
+// SetProxyBasicAuth 设置代理的基本认证，通过 CONNECT 请求的 Proxy-Authorization 头部发送，
+// 只作用于经代理隧道的 HTTPS 请求，代理明文 HTTP 请求时需在代理 URL 中携带用户名和密码
+func (c *Client) SetProxyBasicAuth(username, password string) *Client {
+	if t := c.transport(); t != nil {
+		if t.ProxyConnectHeader == nil {
+			t.ProxyConnectHeader = make(http.Header)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		t.ProxyConnectHeader.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	return c
+}
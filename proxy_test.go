@@ -0,0 +1,189 @@
+package quicklyHttps
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newMockProxy 启动一个直接以自身名称响应所有代理请求的 HTTP 代理
+func newMockProxy(t *testing.T, name string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// deadProxyURL 返回一个已关闭、连接会被拒绝的代理地址
+func deadProxyURL(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "http://" + addr
+}
+
+func newProxyClient(proxies ...string) *Client {
+	c := NewClient().SetBaseURL("http://target.example").SetProxyRotation(proxies)
+	c.Logger = discardLogger{}
+	return c
+}
+
+func TestSetProxyRotation(t *testing.T) {
+	c := newProxyClient(newMockProxy(t, "p1"), newMockProxy(t, "p2"), newMockProxy(t, "p3"))
+	var got []string
+	for i := 0; i < 6; i++ {
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, resp.String())
+	}
+	if want := "p1 p2 p3 p1 p2 p3"; strings.Join(got, " ") != want {
+		t.Errorf("proxies used %v, want round-robin %s", got, want)
+	}
+}
+
+func TestSetProxyRotationFailover(t *testing.T) {
+	clk := useFakeClock(t)
+	c := newProxyClient(deadProxyURL(t), newMockProxy(t, "live")).SetRetryMax(3)
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if got := resp.String(); got != "live" {
+			t.Errorf("request %d served by %q, want failover to live proxy", i, got)
+		}
+	}
+
+	c.proxyRotation.mu.Lock()
+	dead := c.proxyRotation.proxies[0]
+	if !clk.Now().Before(dead.unhealthyUntil) {
+		t.Error("failed proxy not marked unhealthy")
+	}
+	c.proxyRotation.mu.Unlock()
+
+	clk.Advance(proxyCooldown)
+	req, _ := http.NewRequest(http.MethodGet, "http://target.example", nil)
+	var revived bool
+	for range c.proxyRotation.proxies {
+		if u, _ := c.proxyRotation.proxy(req); u == dead.url {
+			revived = true
+		}
+	}
+	if !revived {
+		t.Error("failed proxy not selected again after cooldown")
+	}
+}
+
+// newConnectProxy 启动要求 CONNECT 请求的 key 头部为 want 的代理，校验通过后建立到目标地址的隧道，
+// 返回代理地址和获取每次收到的 key 头部的函数
+func newConnectProxy(t *testing.T, key, want string) (string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(key)
+		mu.Lock()
+		received = append(received, value)
+		mu.Unlock()
+		if r.Method != http.MethodConnect || value != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func TestSetProxyBasicAuth(t *testing.T) {
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:p@ss"))
+	proxyURL, received := newConnectProxy(t, "Proxy-Authorization", want)
+
+	c := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "through tunnel")
+	}).SetProxyURL(proxyURL).SetRetryMax(1)
+	if _, err := c.R().Execute(); err == nil {
+		t.Fatal("request through the authenticated proxy succeeded without credentials")
+	}
+
+	c.SetProxyBasicAuth("user", "p@ss")
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "through tunnel" {
+		t.Errorf("body %q, want through tunnel", got)
+	}
+	got := received()
+	if len(got) != 2 || got[0] != "" || got[1] != want {
+		t.Errorf("proxy received Proxy-Authorization %q, want none then %q", got, want)
+	}
+}
+
+func TestSetProxyConnectHeader(t *testing.T) {
+	proxyURL, received := newConnectProxy(t, "X-Proxy-Token", "route-eu")
+	c := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Proxy-Token"))
+	}).SetProxyURL(proxyURL).SetRetryMax(1)
+
+	header := http.Header{"X-Proxy-Token": {"route-eu"}}
+	c.SetProxyConnectHeader(header)
+	// 调用后修改传入的头部不影响已设置的值
+	header.Set("X-Proxy-Token", "changed")
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "" {
+		t.Errorf("CONNECT header leaked to the target server: %q", got)
+	}
+
+	rejected := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {}).
+		SetProxyURL(proxyURL).SetRetryMax(1).
+		SetProxyBasicAuth("user", "pass").
+		SetProxyConnectHeader(http.Header{"X-Proxy-Token": {"route-us"}})
+	if _, err := rejected.R().Execute(); err == nil {
+		t.Error("request succeeded with a rejected CONNECT header")
+	}
+	if got, want := received(), []string{"route-eu", "route-us"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("proxy received X-Proxy-Token %v, want %v", got, want)
+	}
+	if auth := rejected.transport().ProxyConnectHeader.Get("Proxy-Authorization"); auth != "" {
+		t.Errorf("SetProxyConnectHeader kept Proxy-Authorization %q, want it replaced", auth)
+	}
+}
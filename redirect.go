@@ -0,0 +1,71 @@
+package quicklyHttps
+
+import (
+	"errors"
+	"net/http"
+)
+
+// maxRedirects 与 net/http 默认策略一致的最大重定向次数
+const maxRedirects = 10
+
+// handleRedirect 组合库内置的重定向处理与用户通过 SetCheckRedirect 设置的策略
+func (c *Client) handleRedirect(req *http.Request, via []*http.Request) error {
+	if !c.AutoReferer && len(via) > 0 {
+		// net/http 在调用 CheckRedirect 前已将 Referer 设置为上一跳的 URL，禁用时只保留调用方显式设置的值
+		if referer := via[0].Header.Get("Referer"); referer != "" {
+			req.Header.Set("Referer", referer)
+		} else {
+			req.Header.Del("Referer")
+		}
+	}
+	if c.RedirectPreserveMethod && len(via) > 0 {
+		if err := preserveMethod(req, via[0]); err != nil {
+			return err
+		}
+	}
+	if c.checkRedirect != nil {
+		return c.checkRedirect(req, via)
+	}
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// preserveMethod 使用初始请求的方法和请求体重新发起重定向后的请求
+func preserveMethod(req, orig *http.Request) error {
+	if req.Method == orig.Method {
+		return nil
+	}
+	req.Method = orig.Method
+	if orig.GetBody == nil {
+		return nil
+	}
+	body, err := orig.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = orig.GetBody
+	req.ContentLength = orig.ContentLength
+	if contentType := orig.Header.Get("Content-Type"); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return nil
+}
+
+// SetPreserveMethodOnRedirect 设置 301/302/303 重定向时是否保留原始的请求方法和请求体，
+// 而不是像 net/http 默认行为那样改为 GET
+func (c *Client) SetPreserveMethodOnRedirect(preserve bool) *Client {
+	c.RedirectPreserveMethod = preserve
+	c.Client.CheckRedirect = c.handleRedirect
+	return c
+}
+
+// SetAutoReferer 设置重定向时是否自动将 Referer 设置为上一跳的 URL，默认启用，与 net/http 的行为一致：
+// 从 https 跳转到 http 时不发送，初始请求显式设置的 Referer 保持不变。禁用后重定向的请求只携带显式设置的 Referer
+func (c *Client) SetAutoReferer(enable bool) *Client {
+	c.AutoReferer = enable
+	c.Client.CheckRedirect = c.handleRedirect
+	return c
+}
@@ -0,0 +1,117 @@
+package quicklyHttps
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// newRedirectChain 启动 /a -> /b -> /c 的重定向链，返回客户端和记录各跳 Referer 的函数
+func newRedirectChain(t *testing.T) (*Client, func() map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	referers := make(map[string]string)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		referers[r.URL.Path] = r.Header.Get("Referer")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		}
+	})
+	return c, func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return referers
+	}
+}
+
+func TestSetAutoReferer(t *testing.T) {
+	t.Run("default follows previous hop", func(t *testing.T) {
+		c, referers := newRedirectChain(t)
+		c.SetPreserveMethodOnRedirect(true)
+		if _, err := c.R().Execute("/a"); err != nil {
+			t.Fatal(err)
+		}
+		got := referers()
+		if got["/a"] != "" || got["/b"] != c.BaseURL+"/a" || got["/c"] != c.BaseURL+"/b" {
+			t.Errorf("referers = %v, want each hop to carry the previous URL", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c, referers := newRedirectChain(t)
+		c.SetAutoReferer(false)
+		if _, err := c.R().Execute("/a"); err != nil {
+			t.Fatal(err)
+		}
+		for path, referer := range referers() {
+			if referer != "" {
+				t.Errorf("Referer for %s = %q, want none when disabled", path, referer)
+			}
+		}
+	})
+
+	t.Run("disabled keeps explicit referer", func(t *testing.T) {
+		c, referers := newRedirectChain(t)
+		c.SetAutoReferer(false)
+		if _, err := c.R().SetHeader("Referer", "https://origin.example/").Execute("/a"); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{"/a", "/b", "/c"} {
+			if got := referers()[path]; got != "https://origin.example/" {
+				t.Errorf("Referer for %s = %q, want the explicit value", path, got)
+			}
+		}
+	})
+
+	t.Run("re-enabled", func(t *testing.T) {
+		c, referers := newRedirectChain(t)
+		c.SetAutoReferer(false).SetAutoReferer(true)
+		if _, err := c.R().Execute("/a"); err != nil {
+			t.Fatal(err)
+		}
+		if got := referers()["/c"]; got != c.BaseURL+"/b" {
+			t.Errorf("Referer for /c = %q, want %q", got, c.BaseURL+"/b")
+		}
+	})
+}
+
+func TestSetPreserveMethodOnRedirect(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/echo", http.StatusMovedPermanently)
+		case "/found":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			io.WriteString(w, r.Method+" "+r.Header.Get("Content-Type")+" "+string(body))
+		}
+	}
+	for _, path := range []string{"/moved", "/found"} {
+		t.Run(path, func(t *testing.T) {
+			c := newTestClient(t, handler)
+			resp, err := c.R().SetMethod(http.MethodPost).SetBodyJSONRaw(`{"order":1}`).Execute(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != "GET  " {
+				t.Errorf("default redirect got %q, want net/http to switch to GET without body", got)
+			}
+
+			c.SetPreserveMethodOnRedirect(true)
+			resp, err = c.R().SetMethod(http.MethodPost).SetBodyJSONRaw(`{"order":1}`).Execute(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "POST " + c.contentType(ContentTypeJson) + ` {"order":1}`; resp.String() != want {
+				t.Errorf("preserved redirect got %q, want %q", resp.String(), want)
+			}
+		})
+	}
+}
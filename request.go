@@ -3,12 +3,18 @@ package quicklyHttps
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	urlpkg "net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,23 +31,34 @@ type Request struct {
 	Header      http.Header
 	cookies     []*http.Cookie
 	queryParams map[string]string
+	queryArrays url.Values
 	formParams  url.Values
 	rawClient   *Client
+	contentMD5  bool
+	files       []*multipartFile
+	rawQuery    string
+	rawPath     string
+	discardBody bool
+	bodySeeker  io.ReadSeeker
+	boundary    string
+	bodyFunc    func() (io.ReadCloser, int64, error)
+	bodySize    int64
+	bodyChan    <-chan []byte
+	backoff     Backoff
+	stream      bool // 仅对该请求启用流式模式，如 SSE
+}
+
+// streaming 判断该请求是否按流式模式发送：不限制总耗时，也不缓存响应体用于日志
+func (r *Request) streaming() bool {
+	return r.stream || r.rawClient.StreamMode
 }
 
 // logRequest 记录请求信息
 func (r *Request) logRequest() {
 	logger := r.rawClient.logger()
 	// 将 headers 和 cookies 转换为更易读的格式
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		headers[key] = strings.Join(values, ", ")
-	}
-
-	cookies := make([]string, len(r.cookies))
-	for i, cookie := range r.cookies {
-		cookies[i] = fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)
-	}
+	headers := r.rawClient.logHeaders(r.Header)
+	cookies := r.rawClient.logCookies(r.cookies, "Cookie")
 	// 创建日志消息
 	logMessage := map[string]interface{}{
 		"method":       r.Request.Method,
@@ -50,7 +67,7 @@ func (r *Request) logRequest() {
 		"cookies":      cookies,
 		"query_params": r.queryParams,
 		"form_params":  r.formParams,
-		"body":         r.body,
+		"body":         truncateBody(r.body, r.rawClient.MaxBodyLogSize),
 	}
 
 	// 记录日志
@@ -77,6 +94,14 @@ func (r *Request) SetHeaders(headers map[string]string) *Request {
 	return r
 }
 
+// SetHeadersSlice 按顺序设置多个请求头，避免构造 map 的开销，同名头部以后出现的为准
+func (r *Request) SetHeadersSlice(headers []KV) *Request {
+	for _, kv := range headers {
+		r.Header.Set(kv.Key, kv.Value)
+	}
+	return r
+}
+
 // AddHeader 添加请求头
 func (r *Request) AddHeader(key, value string) *Request {
 	r.Header.Add(key, value)
@@ -112,6 +137,26 @@ func (r *Request) SetCookieRaw(cookie *http.Cookie) *Request {
 	return r
 }
 
+// AddCookies 将 name→value 映射转换为 Cookie 并追加到请求中
+func (r *Request) AddCookies(cookies map[string]string) *Request {
+	for name, value := range cookies {
+		r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	}
+	return r
+}
+
+// SetCookieWithAttributes 解析 Set-Cookie 格式的字符串并添加 Cookie，
+// 属性会被保留，发送时按 Secure、Domain、Path 判断是否携带
+func (r *Request) SetCookieWithAttributes(setCookie string) *Request {
+	cookie, err := parseSetCookie(setCookie)
+	if err != nil {
+		r.rawClient.logger().Error("failed to parse cookie", "error", err)
+		return r
+	}
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
 // SetFormParams 设置多个表单参数
 func (r *Request) SetFormParams(params map[string]string) *Request {
 	for key, value := range params {
@@ -126,6 +171,36 @@ func (r *Request) SetFormParam(key, value string) *Request {
 	return r
 }
 
+// SetFormFromValues 使用 url.Values 替换全部表单参数，支持同名多值
+func (r *Request) SetFormFromValues(values url.Values) *Request {
+	r.formParams = copyValues(values)
+	return r
+}
+
+// AddFormFromValues 将 url.Values 合并到表单参数中，同名参数追加而不覆盖
+func (r *Request) AddFormFromValues(values url.Values) *Request {
+	for key, vals := range values {
+		for _, value := range vals {
+			r.formParams.Add(key, value)
+		}
+	}
+	return r
+}
+
+// SetFormStruct 根据结构体的 form tag 设置表单参数并设置 Content-Type 为表单类型，
+// 支持 omitempty 选项，切片字段会生成多个同名参数
+func (r *Request) SetFormStruct(v interface{}) *Request {
+	values, err := structToValues(v, "form")
+	if err != nil {
+		r.rawClient.logger().Error("failed to encode form struct", "error", err)
+		return r
+	}
+	for key, vals := range values {
+		r.formParams[key] = vals
+	}
+	return r.SetHeader("Content-Type", r.rawClient.contentType(ContentTypeForm))
+}
+
 // SetQueryParams 设置多个查询参数
 func (r *Request) SetQueryParams(params map[string]string) *Request {
 	for key, value := range params {
@@ -134,12 +209,75 @@ func (r *Request) SetQueryParams(params map[string]string) *Request {
 	return r
 }
 
+// SetQueryParamsSlice 按顺序设置多个查询参数，避免构造 map 的开销，同名参数以后出现的为准
+func (r *Request) SetQueryParamsSlice(params []KV) *Request {
+	for _, kv := range params {
+		r.queryParams[kv.Key] = kv.Value
+	}
+	return r
+}
+
 // SetQueryParam 设置单个查询参数
 func (r *Request) SetQueryParam(key, value string) *Request {
 	r.queryParams[key] = value
 	return r
 }
 
+// SetQueryParamInt 设置整数类型的查询参数
+func (r *Request) SetQueryParamInt(key string, value int) *Request {
+	return r.SetQueryParam(key, strconv.Itoa(value))
+}
+
+// SetQueryParamBool 设置布尔类型的查询参数，值为 "true" 或 "false"
+func (r *Request) SetQueryParamBool(key string, value bool) *Request {
+	return r.SetQueryParam(key, strconv.FormatBool(value))
+}
+
+// SetQueryParamFloat 设置浮点数类型的查询参数，使用不带指数的最短表示，如 0.5、1200
+func (r *Request) SetQueryParamFloat(key string, value float64) *Request {
+	return r.SetQueryParam(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// SetQueryParamsFromURL 解析完整的 URL 并将其中的查询参数复制到请求中，多值参数按数组参数设置
+func (r *Request) SetQueryParamsFromURL(rawURL string) *Request {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		r.rawClient.logger().Error("invalid URL", "error", err)
+		return r
+	}
+	for key, values := range u.Query() {
+		if len(values) == 1 {
+			r.SetQueryParam(key, values[0])
+		} else {
+			r.SetQueryParamValues(key, values...)
+		}
+	}
+	return r
+}
+
+// SetQueryParamValues 设置数组类型的查询参数，编码方式由客户端的 SetQueryArrayFormat 决定
+func (r *Request) SetQueryParamValues(key string, values ...string) *Request {
+	if r.queryArrays == nil {
+		r.queryArrays = make(url.Values)
+	}
+	r.queryArrays[key] = values
+	return r
+}
+
+// SetRawQuery 直接设置原始查询字符串，不做任何编码处理，适用于需要精确签名的接口。
+// 设置后请求与客户端的查询参数都会被忽略
+func (r *Request) SetRawQuery(raw string) *Request {
+	r.rawQuery = strings.TrimPrefix(raw, "?")
+	return r
+}
+
+// SetRawPath 直接设置请求的完整路径（包括 BaseURL 中的路径部分），路径按原样发送，不会被重新编码或规范化，
+// 适用于路径段中包含 %2F 等编码字符的接口
+func (r *Request) SetRawPath(path string) *Request {
+	r.rawPath = path
+	return r
+}
+
 // DelQueryParam 删除查询参数
 func (r *Request) DelQueryParam(key string) *Request {
 	delete(r.queryParams, key)
@@ -160,22 +298,110 @@ func (r *Request) SetBodyJSON(data any) *Request {
 			r.rawClient.logger().Error("invalid JSON string", "body", body)
 		}
 	default:
-		jsonData, err := json.Marshal(data)
+		jsonData, err := r.rawClient.jsonMarshal(data)
 		if err != nil {
 			r.rawClient.logger().Error("failed to marshal JSON", "error", err)
 		} else {
 			r.body = string(jsonData)
 		}
 	}
-	r.SetHeader("Content-Type", ContentTypeJson)
+	r.SetHeader("Content-Type", r.rawClient.contentType(ContentTypeJson))
+	setAcceptIfAbsent(r.Header, ContentTypeJson)
+	return r
+}
+
+// SetBodyJSONIndent 将请求体设置为缩进格式的 JSON
+func (r *Request) SetBodyJSONIndent(data interface{}, indent string) *Request {
+	r.SetBodyMarshal(data, func(v interface{}) ([]byte, error) {
+		return json.MarshalIndent(v, "", indent)
+	}, ContentTypeJson)
+	setAcceptIfAbsent(r.Header, ContentTypeJson)
 	return r
 }
 
-// isJSON 判断字符串是否为 JSON 格式
+// SetAccept 设置 Accept 请求头
+func (r *Request) SetAccept(accept string) *Request {
+	return r.SetHeader("Accept", accept)
+}
+
+// isJSON 判断字符串是否为合法的 JSON，包括数字、字符串、布尔值和 null
 func isJSON(str string) bool {
-	str = strings.TrimSpace(str)
-	return (strings.HasPrefix(str, "{") && strings.HasSuffix(str, "}")) ||
-		(strings.HasPrefix(str, "[") && strings.HasSuffix(str, "]"))
+	return json.Valid([]byte(str))
+}
+
+// SetBodyJSONRaw 将字符串原样作为 JSON 请求体，不做格式校验
+func (r *Request) SetBodyJSONRaw(body string) *Request {
+	r.body = body
+	r.SetHeader("Content-Type", r.rawClient.contentType(ContentTypeJson))
+	setAcceptIfAbsent(r.Header, ContentTypeJson)
+	return r
+}
+
+// SetBodyReadSeeker 使用可 Seek 的 Reader 作为请求体，每次重试前 Seek 到开头重新读取，
+// 适合重试大文件上传而无需整体读入内存，size 为请求体长度，小于 0 时通过 Seek 计算
+func (r *Request) SetBodyReadSeeker(body io.ReadSeeker, size int64) *Request {
+	if size < 0 {
+		end, err := body.Seek(0, io.SeekEnd)
+		if err != nil {
+			r.rawClient.logger().Error("failed to determine body size", "error", err)
+			return r
+		}
+		size = end
+	}
+	r.bodySeeker = body
+	r.bodySize = size
+	return r
+}
+
+// SetBodyProvider 设置请求体的提供函数，每次发送（包括每次重试）都会重新调用，
+// 返回新的 Reader 和内容长度（未知时为 -1），适合需要重新打开文件或重新计算签名的场景
+func (r *Request) SetBodyProvider(provider func() (io.ReadCloser, int64, error)) *Request {
+	r.bodyFunc = provider
+	return r
+}
+
+// SetBodyChannel 将从 ch 接收到的数据块依次作为请求体流式发送，ch 关闭时请求体结束，适合上传边生成边发送的大量数据。
+// 数据无法重放，设置后该请求不会重试，也不能与 SetContentMD5 同时使用；请求提前结束时剩余的数据块会被丢弃
+func (r *Request) SetBodyChannel(ch <-chan []byte) *Request {
+	r.bodyChan = ch
+	return r
+}
+
+// channelBody 通过 io.Pipe 将 ch 中的数据块转换为请求体
+func channelBody(ch <-chan []byte) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		for chunk := range ch {
+			if _, err := pw.Write(chunk); err != nil {
+				// 读取端已关闭，继续接收避免发送方阻塞
+				for range ch {
+				}
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return pr
+}
+
+// seekBody 将请求体 Seek 到开头后返回，调用方负责关闭原始的 Reader
+func (r *Request) seekBody() (io.ReadCloser, error) {
+	if _, err := r.bodySeeker.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(io.LimitReader(r.bodySeeker, r.bodySize)), nil
+}
+
+// SetBodyMarshal 使用自定义的编码函数编码请求体并设置 Content-Type，
+// 用于扩展 protobuf、yaml 等格式，编码失败时记录错误并保持原请求体
+func (r *Request) SetBodyMarshal(v interface{}, marshal func(v interface{}) ([]byte, error), contentType string) *Request {
+	data, err := marshal(v)
+	if err != nil {
+		r.rawClient.logger().Error("failed to marshal body", "error", err)
+		return r
+	}
+	r.body = string(data)
+	return r.SetHeader("Content-Type", r.rawClient.contentType(contentType))
 }
 
 // SetBodyBytes 设置请求体为字节数组
@@ -184,53 +410,173 @@ func (r *Request) SetBodyBytes(body []byte) *Request {
 	return r
 }
 
+// SetBodyAny 根据 v 的类型设置请求体和 Content-Type：string 为 text/plain，[]byte 和 io.Reader 为
+// application/octet-stream，url.Values 为表单，其余类型（结构体、map 等）编码为 JSON。
+// io.ReadSeeker 每次重试前 Seek 到开头，其他 io.Reader 会被整体读入内存以便重试
+func (r *Request) SetBodyAny(v interface{}) *Request {
+	switch body := v.(type) {
+	case nil:
+		return r
+	case string:
+		r.SetBody(body)
+		r.SetHeader("Content-Type", r.rawClient.contentType(ContentTypeText))
+	case []byte:
+		r.SetBodyBytes(body)
+		r.SetHeader("Content-Type", ContentTypeStream)
+	case io.ReadSeeker:
+		r.SetBodyReadSeeker(body, -1)
+		r.SetHeader("Content-Type", ContentTypeStream)
+	case io.Reader:
+		data, err := io.ReadAll(body)
+		if err != nil {
+			r.rawClient.logger().Error("failed to read body", "error", err)
+			return r
+		}
+		r.SetBodyBytes(data)
+		r.SetHeader("Content-Type", ContentTypeStream)
+	case url.Values:
+		r.SetFormFromValues(body)
+		r.SetHeader("Content-Type", r.rawClient.contentType(ContentTypeForm))
+	default:
+		r.SetBodyJSON(body)
+	}
+	return r
+}
+
 // prepareRequestBody 准备请求体
-func (r *Request) prepareRequestBody() *bytes.Reader {
+func (r *Request) prepareRequestBody() []byte {
 	if len(r.formParams) > 0 {
-		return bytes.NewReader([]byte(r.formParams.Encode()))
+		return []byte(r.formParams.Encode())
 	}
-	return bytes.NewReader([]byte(r.body))
+	return []byte(r.body)
 }
 
 // prepareRequestURL 准备请求 URL
 func (r *Request) prepareRequestURL() string {
 	urlPath := strings.TrimPrefix(r.urlPoint, "/")
-	if len(r.queryParams) > 0 {
-		queryParams := url.Values{}
-		for key, value := range r.queryParams {
-			queryParams.Add(key, value)
+	if r.rawQuery != "" {
+		return urlPath
+	}
+	queryParams := url.Values{}
+	for key, value := range r.rawClient.BaseURLQueryParams {
+		queryParams.Set(key, value)
+	}
+	for key, value := range r.queryParams {
+		queryParams.Set(key, value)
+	}
+	for key, values := range r.queryArrays {
+		setArray(queryParams, key, values, r.rawClient.QueryArrayFormat)
+	}
+	if len(queryParams) > 0 {
+		if strings.Contains(urlPath, "?") {
+			urlPath += "&" + queryParams.Encode()
+		} else {
+			urlPath += "?" + queryParams.Encode()
 		}
-		urlPath += "?" + queryParams.Encode()
 	}
 	return urlPath
 }
 
 func (r *Request) newRequest() (*http.Request, error) {
-	u, err := urlpkg.Parse(fmt.Sprintf("%s/%s", r.rawClient.BaseURL, r.prepareRequestURL()))
+	rawURL := r.rawClient.BaseURL
+	if urlPath := r.prepareRequestURL(); isAbsoluteURL(r.urlPoint) {
+		rawURL = urlPath
+	} else if urlPath == "" || strings.HasPrefix(urlPath, "?") {
+		rawURL += urlPath
+	} else {
+		rawURL += "/" + urlPath
+	}
+	u, err := urlpkg.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
 	u.Host = removeEmptyPort(u.Host)
+	if r.rawQuery != "" {
+		u.RawQuery = r.rawQuery
+	}
+	if r.rawPath != "" {
+		path, err := urlpkg.PathUnescape(r.rawPath)
+		if err != nil {
+			return nil, err
+		}
+		u.Path, u.RawPath = path, r.rawPath
+		// RawPath 不是合法的编码形式时会被 EscapedPath 忽略，改用 Opaque 原样写出
+		if u.EscapedPath() != r.rawPath {
+			u.Opaque = r.rawPath
+		}
+	}
+	if r.method == "" {
+		return nil, fmt.Errorf("HTTP method is not set")
+	}
 
 	var reqBody io.ReadCloser
 	var contentLength int64
-	if r.GetBody != nil {
-		reqBody, err = r.GetBody()
-		if err != nil {
+	var contentType string
+	var md5Sum string
+	getBody := r.GetBody
+	switch {
+	case getBody != nil:
+		// 请求体在计算 Content-MD5 之后统一获取
+	case r.bodyFunc != nil:
+		if reqBody, contentLength, err = r.bodyFunc(); err != nil {
 			return nil, err
 		}
-	} else {
+		if r.contentMD5 {
+			if reqBody, contentLength, md5Sum, err = bufferMD5(reqBody); err != nil {
+				return nil, err
+			}
+		}
+		getBody = func() (io.ReadCloser, error) {
+			body, _, err := r.bodyFunc()
+			return body, err
+		}
+	case r.bodySeeker != nil:
+		contentLength = r.bodySize
+		getBody = r.seekBody
+	case r.bodyChan != nil:
+		contentLength = -1
+		reqBody = channelBody(r.bodyChan)
+	case len(r.files) > 0:
+		mw := multipart.NewWriter(nil)
+		if r.boundary != "" {
+			_ = mw.SetBoundary(r.boundary)
+		}
+		boundary := mw.Boundary()
+		contentType = mw.FormDataContentType()
+		contentLength = -1
+		if r.multipartReplayable() {
+			getBody = func() (io.ReadCloser, error) {
+				return r.multipartBody(boundary), nil
+			}
+		} else {
+			reqBody = r.multipartBody(boundary)
+		}
+	default:
 		prepareBody := r.prepareRequestBody()
-		contentLength = int64(prepareBody.Len())
-		reqBody = io.NopCloser(prepareBody)
-		r.GetBody = func() (io.ReadCloser, error) {
-			return io.NopCloser(bytes.NewReader([]byte(r.body))), nil
+		contentLength = int64(len(prepareBody))
+		reqBody = io.NopCloser(bytes.NewReader(prepareBody))
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(prepareBody)), nil
 		}
 	}
-
-	if r.method == "" {
-		return nil, fmt.Errorf("HTTP method is not set")
+	if r.contentMD5 && md5Sum == "" {
+		// 先完整读取一遍请求体计算 MD5 再获取实际发送的请求体，避免同一个 Reader 被两个 goroutine 同时读取
+		if getBody == nil {
+			if reqBody != nil {
+				reqBody.Close()
+			}
+			return nil, fmt.Errorf("Content-MD5 requires a replayable request body")
+		}
+		if md5Sum, err = bodyMD5(getBody); err != nil {
+			return nil, err
+		}
 	}
+	if reqBody == nil && getBody != nil {
+		if reqBody, err = getBody(); err != nil {
+			return nil, err
+		}
+	}
+
 	if r.ctx == nil {
 		r.ctx = context.Background()
 	}
@@ -244,11 +590,33 @@ func (r *Request) newRequest() (*http.Request, error) {
 		ProtoMinor:    1,
 		ContentLength: contentLength,
 		Body:          reqBody,
-		GetBody:       r.GetBody,
+		GetBody:       getBody,
 	}
 	req = req.WithContext(r.ctx)
+	for key, values := range r.rawClient.DefaultHeaders {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = append([]string(nil), values...)
+		}
+	}
+	if r.rawClient.AutoIdempotencyKey && req.Header.Get(headerIdempotencyKey) == "" &&
+		(r.method == http.MethodPost || r.method == http.MethodPatch) {
+		req.Header.Set(headerIdempotencyKey, newUUID())
+	}
+	if r.rawClient.MethodOverride &&
+		(r.method == http.MethodPut || r.method == http.MethodPatch || r.method == http.MethodDelete) {
+		req.Header.Set(headerMethodOverride, r.method)
+		req.Method = http.MethodPost
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.contentMD5 {
+		req.Header.Set("Content-MD5", md5Sum)
+	}
 	for _, cookie := range r.cookies {
-		req.AddCookie(cookie)
+		if cookieMatchesURL(cookie, u) {
+			req.AddCookie(cookie)
+		}
 	}
 
 	if r.rawClient.UserInfo != nil { // takes precedence
@@ -261,14 +629,144 @@ func (r *Request) newRequest() (*http.Request, error) {
 	return req, nil
 }
 
+// SetIdempotencyKey 设置 Idempotency-Key 请求头，重试时沿用同一个值以便服务端去重
+func (r *Request) SetIdempotencyKey(key string) *Request {
+	return r.SetHeader(headerIdempotencyKey, key)
+}
+
+// SetIfModifiedSince 设置 If-Modified-Since 条件请求头，资源在 t 之后未修改时服务端返回 304
+func (r *Request) SetIfModifiedSince(t time.Time) *Request {
+	return r.SetHeader("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+}
+
+// SetIfNoneMatch 设置 If-None-Match 条件请求头，未加引号的 etag 会自动加上引号，
+// 弱校验的 W/"..." 和 * 原样使用，资源的 ETag 匹配时服务端返回 304
+func (r *Request) SetIfNoneMatch(etag string) *Request {
+	if etag != "*" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = strconv.Quote(etag)
+	}
+	return r.SetHeader("If-None-Match", etag)
+}
+
+// DiscardBody 设置收到响应后直接读取并丢弃响应体，不做缓存，适用于只关心状态码的请求
+func (r *Request) DiscardBody() *Request {
+	r.discardBody = true
+	return r
+}
+
+// SetContentMD5 根据最终的请求体计算 MD5 并设置 Content-MD5 请求头，
+// 计算时需要额外读取一遍请求体，请求体无法重放（如不支持 Seek 的 SetFileReader）时发送请求返回错误
+func (r *Request) SetContentMD5() *Request {
+	r.contentMD5 = true
+	return r
+}
+
+// bodyMD5 计算请求体的 base64 编码 MD5 值
+func bodyMD5(getBody func() (io.ReadCloser, error)) (string, error) {
+	body, err := getBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	hash := md5.New()
+	if _, err = io.Copy(hash, body); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// bufferMD5 将请求体整体读入内存并计算 MD5，用于每次调用内容都可能不同的 SetBodyProvider，
+// 保证 Content-MD5 与实际发送的请求体一致
+func bufferMD5(body io.ReadCloser) (io.ReadCloser, int64, string, error) {
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	sum := md5.Sum(data)
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// rewindBody 在重试前重新获取请求体，设置了 SetContentMD5 时同时重新计算 Content-MD5
+func (r *Request) rewindBody() error {
+	if r.bodyFunc != nil {
+		body, contentLength, err := r.bodyFunc()
+		if err != nil {
+			return err
+		}
+		if r.contentMD5 {
+			var sum string
+			if body, contentLength, sum, err = bufferMD5(body); err != nil {
+				return err
+			}
+			r.Request.Header.Set("Content-MD5", sum)
+		}
+		r.Request.Body = body
+		r.Request.ContentLength = contentLength
+		return nil
+	}
+	if r.Request.GetBody == nil {
+		if r.Request.Body == nil || r.Request.Body == http.NoBody {
+			return nil
+		}
+		return fmt.Errorf("request body cannot be replayed for retry")
+	}
+	if r.contentMD5 {
+		sum, err := bodyMD5(r.Request.GetBody)
+		if err != nil {
+			return err
+		}
+		r.Request.Header.Set("Content-MD5", sum)
+	}
+	body, err := r.Request.GetBody()
+	if err != nil {
+		return err
+	}
+	r.Request.Body = body
+	return nil
+}
+
 func (r *Request) SetContext(ctx context.Context) *Request {
 	r.ctx = ctx
 	return r
 }
 
+// When 在 cond 为 true 时调用 fn 配置请求，便于在链式调用中按条件设置
+func (r *Request) When(cond bool, fn func(*Request)) *Request {
+	if cond {
+		fn(r)
+	}
+	return r
+}
+
+// SetURL 设置请求的地址，可以是相对于 BaseURL 的路径，也可以是绝对 URL（此时忽略 BaseURL），
+// 设置后可直接调用不带参数的 Execute()
+func (r *Request) SetURL(url string) *Request {
+	if isAbsoluteURL(url) {
+		r.urlPoint = url
+	} else {
+		r.urlPoint = strings.TrimPrefix(url, "/")
+	}
+	return r
+}
+
+// isAbsoluteURL 判断是否为包含协议和主机的绝对 URL
+func isAbsoluteURL(rawURL string) bool {
+	u, err := urlpkg.Parse(rawURL)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+// Send 直接请求客户端的 BaseURL，不追加路径
+func (r *Request) Send() (*Response, error) {
+	return r.Execute("")
+}
+
 // Execute 执行请求并返回响应
-func (r *Request) Execute(urlPath string) (*Response, error) {
-	r.urlPoint = strings.TrimPrefix(urlPath, "/")
+// 省略 urlPath 时使用 SetURL 设置的地址，绝对 URL 不会拼接 BaseURL
+func (r *Request) Execute(urlPath ...string) (*Response, error) {
+	if len(urlPath) > 0 {
+		r.SetURL(urlPath[0])
+	}
 	request, err := r.newRequest()
 	if err != nil {
 		r.rawClient.logger().Error("failed to build HTTP request", "error", err)
@@ -278,11 +776,166 @@ func (r *Request) Execute(urlPath string) (*Response, error) {
 		request = r.rawClient.handleRequestResultFunc(request)
 	}
 	r.Request = request
-	for i := 0; i < r.rawClient.RetryMax; i++ {
+	if r.rawClient.SingleFlight && (r.method == http.MethodGet || r.method == http.MethodHead) {
+		return r.executeSingleFlight()
+	}
+	return r.executeWithRetry()
+}
+
+// executeSingleFlight 合并方法、URL 和请求头都相同的并发请求，只发出一次实际请求，
+// 响应体会被缓存并由所有调用方共享
+func (r *Request) executeSingleFlight() (*Response, error) {
+	key := singleFlightKey(r.Request)
+	v, err, shared := r.rawClient.singleFlight.Do(key, func() (interface{}, error) {
+		response, err := r.executeWithRetry()
+		if err != nil {
+			return nil, err
+		}
+		response.Body()
+		// 结果可能被多个调用方共享，其缓冲区不能归还到缓冲池
+		response.buf = nil
+		return response, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := v.(*Response)
+	if shared && response.rawRequest != r {
+		return response.share(r), nil
+	}
+	return response, nil
+}
+
+// singleFlightKey 返回由方法、URL 和全部请求头组成的合并键，
+// 携带不同认证信息或 Cookie 的请求不会被合并，避免响应在不同用户之间共享
+func singleFlightKey(req *http.Request) string {
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.URL.String())
+	for _, name := range names {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		for _, value := range req.Header[name] {
+			b.WriteByte(0)
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// executeWithRetry 发送已构建好的请求，失败时按客户端设置重试
+func (r *Request) executeWithRetry() (*Response, error) {
+	var err error
+	budget := r.rawClient.retryBudget
+	if budget != nil {
+		budget.recordRequest()
+	}
+	retryStartedAt := now()
+	timeoutRetries := 0
+	var attemptErrs []error
+	// statusResponse 上一次因可重试状态码而失败的响应，不再重试时直接返回
+	var statusResponse *Response
+	attempts := r.rawClient.RetryMax
+	if r.bodyChan != nil && attempts > 1 {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if budget != nil && !budget.acquire() {
+				r.rawClient.logger().Warn("retry budget exhausted")
+				break
+			}
+			if statusResponse != nil {
+				statusResponse.discard()
+				statusResponse = nil
+			}
+			if err = r.rewindBody(); err != nil {
+				return nil, err
+			}
+		}
 		response, ok := r.Do()
+		var circuitErr *CircuitOpenError
+		if errors.As(ok, &circuitErr) {
+			return nil, ok
+		}
+		var validationErr *ValidationError
+		if errors.As(ok, &validationErr) {
+			return response, ok
+		}
 		if ok == nil && response.Response != nil {
-			return response, nil
+			if r.rawClient.retryableStatus[response.StatusCode()] {
+				statusResponse = response
+				ok = fmt.Errorf("retryable status code %d", response.StatusCode())
+			} else if !r.rawClient.RetryOnTruncatedBody || r.streaming() || !response.isTruncated() {
+				return response, nil
+			} else {
+				r.rawClient.logger().Warn("response body truncated, retrying", "error", response.Err)
+				ok = response.Err
+			}
+		}
+		attemptErrs = append(attemptErrs, fmt.Errorf("attempt %d: %w", i+1, ok))
+		if ok != nil && isTimeoutError(ok) && r.rawClient.TimeoutRetryMax >= 0 {
+			if timeoutRetries >= r.rawClient.TimeoutRetryMax {
+				break
+			}
+			timeoutRetries++
+		}
+		if i+1 >= attempts {
+			break
+		}
+		wait := r.retryWait(i+1, response)
+		if r.retryElapsed(retryStartedAt, wait) {
+			break
+		}
+		if err = sleepContext(r.ctx, wait); err != nil {
+			if statusResponse != nil {
+				statusResponse.discard()
+			}
+			return nil, err
+		}
+	}
+	if statusResponse != nil {
+		return statusResponse, nil
+	}
+	if len(attemptErrs) == 0 {
+		return nil, errors.New("failed to execute request")
+	}
+	return nil, fmt.Errorf("failed to execute request: %w", errors.Join(attemptErrs...))
+}
+
+// retryElapsed 判断加上下一次等待后重试累计耗时是否会超过客户端设置的上限
+func (r *Request) retryElapsed(startedAt time.Time, wait time.Duration) bool {
+	maxElapsed := r.rawClient.MaxRetryElapsedTime
+	return maxElapsed > 0 && now().Sub(startedAt)+wait >= maxElapsed
+}
+
+// retryWait 返回第 attempt 次重试前需要等待的时间，响应带有 Retry-After 时优先遵循（不超过 RetryAfterCap），
+// 否则使用退避策略，未设置退避策略时不等待
+func (r *Request) retryWait(attempt int, resp *Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		if limit := r.rawClient.RetryAfterCap; limit > 0 && wait > limit {
+			wait = limit
 		}
+		return wait
+	}
+	backoff := r.backoff
+	if backoff == nil {
+		backoff = r.rawClient.backoff
+	}
+	if backoff == nil {
+		return 0
 	}
-	return nil, fmt.Errorf("failed to execute request")
+	return backoff.Next(attempt, resp)
+}
+
+// SetRetryWait 设置该请求的重试等待时间，从 min 开始每次翻倍且不超过 max，优先于客户端的 SetBackoff 设置
+func (r *Request) SetRetryWait(min, max time.Duration) *Request {
+	r.backoff = ExponentialBackoff{Min: min, Max: max}
+	return r
 }
@@ -0,0 +1,1068 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// md5Base64 返回 data 的 base64 编码 MD5 值
+func md5Base64(data []byte) string {
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// checkContentMD5 校验请求的 Content-MD5 与请求体一致，不一致时返回 400
+func checkContentMD5(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, _ := io.ReadAll(r.Body)
+	if got, want := r.Header.Get("Content-MD5"), md5Base64(body); got != want {
+		http.Error(w, fmt.Sprintf("Content-MD5 %q, body MD5 %q", got, want), http.StatusBadRequest)
+		return body, false
+	}
+	return body, true
+}
+
+func TestSetContentMD5(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if body, ok := checkContentMD5(w, r); ok {
+			w.Write(body)
+		}
+	})
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"string body", c.R().SetBody("hello world"), "hello world"},
+		{"form body", c.R().SetFormParams(map[string]string{"a": "1"}), "a=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.SetMethod(http.MethodPost).SetContentMD5().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode() != http.StatusOK || resp.String() != tt.want {
+				t.Errorf("got %d %q, want 200 %q", resp.StatusCode(), resp.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetContentMD5RecomputedOnRetry(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			hijackClose(w)
+			return
+		}
+		if body, ok := checkContentMD5(w, r); ok {
+			w.Write(body)
+		}
+	})
+	var calls int32
+	provider := func() (io.ReadCloser, int64, error) {
+		body := fmt.Sprintf("body for call %d", atomic.AddInt32(&calls, 1))
+		return io.NopCloser(strings.NewReader(body)), -1, nil
+	}
+	resp, err := c.R().SetMethod(http.MethodPut).SetBodyProvider(provider).SetContentMD5().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+	}
+	if got, want := resp.String(), fmt.Sprintf("body for call %d", atomic.LoadInt32(&calls)); got != want {
+		t.Errorf("server received %q, want the latest provider body %q", got, want)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("got %d attempts, want 2", n)
+	}
+}
+
+func TestSetFormFromValues(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		io.WriteString(w, r.PostForm.Encode())
+	})
+	resp, err := c.R().SetMethod(http.MethodPost).
+		SetHeader("Content-Type", ContentTypeForm).
+		SetFormParam("dropped", "x").
+		SetFormFromValues(url.Values{"tag": {"a", "b"}, "id": {"1"}}).
+		AddFormFromValues(url.Values{"tag": {"c"}}).
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := url.ParseQuery(resp.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"tag": {"a", "b", "c"}, "id": {"1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("server received %v, want %v", got, want)
+	}
+}
+
+func TestSetAccept(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Accept"))
+	})
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"no body", c.R(), ""},
+		{"explicit", c.R().SetAccept("text/csv"), "text/csv"},
+		{"json body", c.R().SetBodyJSON(map[string]int{"a": 1}), ContentTypeJson},
+		{"json body keeps explicit accept", c.R().SetAccept("application/vnd.api+json").SetBodyJSON(map[string]int{"a": 1}), "application/vnd.api+json"},
+		{"json indent body", c.R().SetBodyJSONIndent(map[string]int{"a": 1}, "  "), ContentTypeJson},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.SetMethod(http.MethodPost).Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("Accept = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetJSONEscapeHTML(t *testing.T) {
+	var body string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}
+	data := map[string]string{"html": "<b>bold</b> & more"}
+
+	c := newTestClient(t, handler)
+	if _, err := c.R().SetBodyJSON(data).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `\u003cb\u003e`) {
+		t.Errorf("default body %s, want HTML escaped", body)
+	}
+
+	c = newTestClient(t, handler).SetJSONEscapeHTML(false)
+	if _, err := c.R().SetBodyJSON(data).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"html":"<b>bold</b> & more"}`; body != want {
+		t.Errorf("request body %s, want %s", body, want)
+	}
+
+	c = newTestClient(t, handler).SetJSONEscapeHTML(false).SetBodyJSON(data)
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"html":"<b>bold</b> & more"}`; body != want {
+		t.Errorf("client body %s, want %s", body, want)
+	}
+}
+
+func TestSetRawQuery(t *testing.T) {
+	var rawQuery string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	})
+	c.SetQueryParam("client", "1")
+
+	// 预签名的查询字符串，重新编码会改变参数顺序和 %2F、+ 的写法导致签名失效
+	const signed = "z=last&a=first&path=a%2Fb&q=x+y&Signature=ab%2Bcd%3D"
+	if _, err := c.R().SetQueryParam("ignored", "1").SetRawQuery("?" + signed).Execute("files"); err != nil {
+		t.Fatal(err)
+	}
+	if rawQuery != signed {
+		t.Errorf("RawQuery = %q, want %q unchanged", rawQuery, signed)
+	}
+
+	if _, err := c.R().SetQueryParam("q", "x y").Execute("files"); err != nil {
+		t.Fatal(err)
+	}
+	if rawQuery == signed || !strings.Contains(rawQuery, "client=1") {
+		t.Errorf("RawQuery = %q without SetRawQuery, want encoded params", rawQuery)
+	}
+}
+
+func TestSetCookieWithAttributes(t *testing.T) {
+	cookie, err := parseSetCookie("sid=abc123; Path=/api; Domain=example.com; Secure; HttpOnly; SameSite=Strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &http.Cookie{Name: "sid", Value: "abc123", Path: "/api", Domain: "example.com",
+		Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode}
+	if cookie.Name != want.Name || cookie.Value != want.Value || cookie.Path != want.Path || cookie.Domain != want.Domain ||
+		cookie.Secure != want.Secure || cookie.HttpOnly != want.HttpOnly || cookie.SameSite != want.SameSite {
+		t.Errorf("parsed cookie %+v, want %+v", cookie, want)
+	}
+	if _, err := parseSetCookie("no-equals-sign"); err == nil {
+		t.Error("expected error for invalid cookie string")
+	}
+
+	var got string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Cookie")
+	})
+	tests := []struct {
+		path, want string
+	}{
+		{"/api", "path=p"},
+		{"/api/users", "path=p"},
+		{"/apix", ""},
+		{"/other", ""},
+	}
+	for _, tt := range tests {
+		got = ""
+		_, err := c.R().
+			SetCookieWithAttributes("path=p; Path=/api").
+			SetCookieWithAttributes("secure=s; Secure").
+			SetCookieWithAttributes("domain=d; Domain=other.example").
+			Execute(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Cookie for %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCookiePathMatch(t *testing.T) {
+	tests := []struct {
+		cookiePath, path string
+		want             bool
+	}{
+		{"/", "/", true},
+		{"/", "/anything", true},
+		{"/api", "/api", true},
+		{"/api", "/api/v1", true},
+		{"/api", "/apix", false},
+		{"/api/", "/api/v1", true},
+		{"/api/", "/api", false},
+		{"/api/v1", "/api", false},
+	}
+	for _, tt := range tests {
+		if got := cookiePathMatch(tt.cookiePath, tt.path); got != tt.want {
+			t.Errorf("cookiePathMatch(%q, %q) = %v, want %v", tt.cookiePath, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetIdempotencyKey(t *testing.T) {
+	useFakeClock(t)
+	var mu sync.Mutex
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		n := len(keys)
+		mu.Unlock()
+		if n%3 != 0 {
+			hijackClose(w)
+		}
+	}).SetRetryMax(3)
+	sent := func(req *Request) []string {
+		t.Helper()
+		mu.Lock()
+		keys = nil
+		mu.Unlock()
+		if _, err := req.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), keys...)
+	}
+
+	got := sent(c.R().SetMethod(http.MethodPost).SetIdempotencyKey("order-42"))
+	if len(got) != 3 || got[0] != "order-42" || got[1] != "order-42" || got[2] != "order-42" {
+		t.Errorf("keys sent %q, want order-42 on every attempt", got)
+	}
+
+	if got := sent(c.R().SetMethod(http.MethodPost)); got[0] != "" {
+		t.Errorf("key %q sent without SetAutoIdempotencyKey", got[0])
+	}
+
+	c.SetAutoIdempotencyKey(true)
+	got = sent(c.R().SetMethod(http.MethodPost))
+	if len(got) != 3 || got[0] == "" || got[1] != got[0] || got[2] != got[0] {
+		t.Errorf("auto keys sent %q, want one generated key reused across attempts", got)
+	}
+	if again := sent(c.R().SetMethod(http.MethodPost)); again[0] == got[0] {
+		t.Errorf("two requests shared auto key %q", got[0])
+	}
+	if get := sent(c.R()); get[0] != "" {
+		t.Errorf("auto key %q sent on GET", get[0])
+	}
+}
+
+// waitFor 轮询直到 cond 成立，超时返回 false
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestSetSingleFlight(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		io.WriteString(w, "user:"+r.Header.Get("Authorization")+" cookie:"+r.Header.Get("Cookie"))
+	}).SetSingleFlight(true)
+
+	type result struct {
+		body string
+		err  error
+	}
+	fire := func(n int, build func() *Request) <-chan result {
+		results := make(chan result, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				resp, err := build().Execute("/items")
+				if err != nil {
+					results <- result{err: err}
+					return
+				}
+				results <- result{body: resp.String()}
+			}()
+		}
+		return results
+	}
+
+	t.Run("identical requests coalesce", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		release = make(chan struct{})
+		results := fire(20, func() *Request { return c.R().SetHeader("Authorization", "alice") })
+		if !waitFor(func() bool { return atomic.LoadInt32(&hits) == 1 }) {
+			t.Fatal("request never reached the server")
+		}
+		// 留出时间让其余调用方加入正在进行的请求
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		for i := 0; i < 20; i++ {
+			res := <-results
+			if res.err != nil || res.body != "user:alice cookie:" {
+				t.Errorf("result %q, %v", res.body, res.err)
+			}
+		}
+		if n := atomic.LoadInt32(&hits); n != 1 {
+			t.Errorf("server received %d requests, want 1", n)
+		}
+	})
+
+	t.Run("different credentials not shared", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		release = make(chan struct{})
+		alice := fire(1, func() *Request { return c.R().SetHeader("Authorization", "alice") })
+		bob := fire(1, func() *Request { return c.R().SetHeader("Authorization", "bob") })
+		carol := fire(1, func() *Request { return c.R().SetHeader("Authorization", "alice").SetCookie("sid=carol") })
+		if !waitFor(func() bool { return atomic.LoadInt32(&hits) == 3 }) {
+			t.Errorf("server received %d requests, want 3 separate calls", atomic.LoadInt32(&hits))
+		}
+		close(release)
+		for ch, want := range map[<-chan result]string{
+			alice: "user:alice cookie:", bob: "user:bob cookie:", carol: "user:alice cookie:sid=carol",
+		} {
+			if res := <-ch; res.err != nil || res.body != want {
+				t.Errorf("got %q, %v; want %q", res.body, res.err, want)
+			}
+		}
+	})
+}
+
+func TestSetFormStruct(t *testing.T) {
+	var got url.Values
+	var contentType string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		r.ParseForm()
+		got = r.PostForm
+	})
+	c.SetDefaultCharset("utf-8")
+	type search struct {
+		Query    string   `form:"q"`
+		Page     int      `form:"page,omitempty"`
+		Note     *string  `form:"note,omitempty"`
+		Tags     []string `form:"tag"`
+		Internal string   `form:"-"`
+		Exact    bool
+	}
+	note := "urgent"
+	tests := []struct {
+		name string
+		in   interface{}
+		want url.Values
+	}{
+		{"all fields", &search{Query: "go", Page: 2, Note: &note, Tags: []string{"a", "b"}, Internal: "x", Exact: true},
+			url.Values{"q": {"go"}, "page": {"2"}, "note": {"urgent"}, "tag": {"a", "b"}, "Exact": {"true"}}},
+		{"optional omitted", search{Query: "go"},
+			url.Values{"q": {"go"}, "Exact": {"false"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.R().SetMethod(http.MethodPost).SetFormStruct(tt.in).Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if want := c.contentType(ContentTypeForm); contentType != want {
+				t.Errorf("Content-Type = %q, want %q", contentType, want)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("form = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestSend(t *testing.T) {
+	var gotURI string
+	srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+	})
+	tests := []struct {
+		name, baseURL string
+		build         func(*Request) *Request
+		want          string
+	}{
+		{"base path", srv.BaseURL + "/api/v1/status", nil, "/api/v1/status"},
+		{"trailing slash trimmed by SetBaseURL", srv.BaseURL + "/api/", nil, "/api"},
+		{"host only", srv.BaseURL, nil, "/"},
+		{"query params", srv.BaseURL + "/search", func(r *Request) *Request { return r.SetQueryParam("q", "go") }, "/search?q=go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient().SetBaseURL(tt.baseURL)
+			c.Logger = discardLogger{}
+			req := c.R()
+			if tt.build != nil {
+				req = tt.build(req)
+			}
+			if _, err := req.Send(); err != nil {
+				t.Fatal(err)
+			}
+			if gotURI != tt.want {
+				t.Errorf("request URI = %q, want %q", gotURI, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddCookies(t *testing.T) {
+	var got []*http.Cookie
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Cookies()
+	})
+	want := map[string]string{"session": "abc", "theme": "dark", "lang": "zh-CN"}
+	if _, err := c.R().SetCookie("existing=1").AddCookies(want).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	received := make(map[string]string, len(got))
+	for _, cookie := range got {
+		received[cookie.Name] = cookie.Value
+	}
+	want["existing"] = "1"
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("cookies received %v, want %v", received, want)
+	}
+}
+
+func TestDiscardBody(t *testing.T) {
+	payload := strings.Repeat("ignored ", 8<<10)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	})
+	var reused []bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) },
+	})
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().SetContext(ctx).DiscardBody().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			t.Errorf("status %d", resp.StatusCode())
+		}
+		if body := resp.Body(); len(body) != 0 {
+			t.Errorf("discarded response kept %d body bytes", len(body))
+		}
+	}
+	if len(reused) != 3 || reused[0] || !reused[1] || !reused[2] {
+		t.Errorf("connection reuse %v, want [false true true]", reused)
+	}
+}
+
+// countingSeeker 记录 Seek 到开头的次数
+type countingSeeker struct {
+	*strings.Reader
+	rewinds int32
+}
+
+func (s *countingSeeker) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		atomic.AddInt32(&s.rewinds, 1)
+	}
+	return s.Reader.Seek(offset, whence)
+}
+
+func TestSetBodyReadSeeker(t *testing.T) {
+	useFakeClock(t)
+	const content = "hello world"
+	var mu sync.Mutex
+	var bodies []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		ok := true
+		if r.Header.Get("Content-MD5") != "" {
+			body, ok = checkContentMD5(w, r)
+		} else {
+			body, _ = io.ReadAll(r.Body)
+		}
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		n := len(bodies)
+		mu.Unlock()
+		if ok && n == 1 {
+			hijackClose(w)
+		}
+	}).SetRetryMax(3)
+
+	t.Run("retry rewinds", func(t *testing.T) {
+		bodies = nil
+		seeker := &countingSeeker{Reader: strings.NewReader(content)}
+		// 先读走一部分，请求仍应从头发送
+		io.CopyN(io.Discard, seeker, 5)
+		resp, err := c.R().SetMethod(http.MethodPut).SetBodyReadSeeker(seeker, -1).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSuccess() {
+			t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+		}
+		if len(bodies) != 2 || bodies[0] != content || bodies[1] != content {
+			t.Errorf("server received %q, want %q on both attempts", bodies, content)
+		}
+		if n := atomic.LoadInt32(&seeker.rewinds); n != 2 {
+			t.Errorf("seeker rewound %d times, want once per attempt", n)
+		}
+	})
+
+	t.Run("with Content-MD5", func(t *testing.T) {
+		bodies = nil
+		resp, err := c.R().SetMethod(http.MethodPut).SetContentMD5().
+			SetBodyReadSeeker(strings.NewReader(content), int64(len(content))).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSuccess() {
+			t.Fatalf("status %d: %s", resp.StatusCode(), resp.String())
+		}
+		if len(bodies) != 2 || bodies[0] != content || bodies[1] != content {
+			t.Errorf("server received %q, want %q on both attempts", bodies, content)
+		}
+	})
+}
+
+func TestSetURL(t *testing.T) {
+	var gotURI string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+	}
+	c := newTestClient(t, handler)
+	// 另一台服务器，用于验证绝对 URL 不拼接 BaseURL
+	other := newTestClient(t, handler)
+	c.SetBaseURL(c.BaseURL + "/api")
+
+	tests := []struct {
+		name, url, want string
+	}{
+		{"relative path", "users/1", "/api/users/1"},
+		{"leading slash", "/users/2", "/api/users/2"},
+		{"with query", "search?q=go", "/api/search?q=go"},
+		{"absolute URL bypasses BaseURL", other.BaseURL + "/health", "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.R().SetURL(tt.url).Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if gotURI != tt.want {
+				t.Errorf("request URI = %q, want %q", gotURI, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.R().SetURL("ignored").Execute("override"); err != nil {
+		t.Fatal(err)
+	}
+	if gotURI != "/api/override" {
+		t.Errorf("request URI = %q, want Execute argument to override SetURL", gotURI)
+	}
+}
+
+func TestSetBodyJSONIndent(t *testing.T) {
+	var body, contentType string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType = string(data), r.Header.Get("Content-Type")
+	})
+	if _, err := c.R().SetMethod(http.MethodPost).SetBodyJSONIndent(map[string]int{"a": 1, "b": 2}, "  ").Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"a\": 1,\n  \"b\": 2\n}"; body != want {
+		t.Errorf("body %q, want %q", body, want)
+	}
+	if !strings.HasPrefix(contentType, ContentTypeJson) {
+		t.Errorf("Content-Type %q, want %s", contentType, ContentTypeJson)
+	}
+}
+
+func TestSetQueryArrayFormat(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RawQuery)
+	})
+	tests := []struct {
+		name   string
+		format ArrayFormat
+		want   string
+	}{
+		{"repeat", ArrayFormatRepeat, "a=1&a=2"},
+		{"brackets", ArrayFormatBrackets, "a%5B%5D=1&a%5B%5D=2"},
+		{"comma", ArrayFormatComma, "a=1%2C2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.SetQueryArrayFormat(tt.format).R().SetQueryParamValues("a", "1", "2").Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("query %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// trackedBody 记录请求体是否被关闭
+type trackedBody struct {
+	io.Reader
+	closed int32
+}
+
+func (b *trackedBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	return nil
+}
+
+func TestSetBodyProviderFreshReaderPerAttempt(t *testing.T) {
+	const body = "provided body"
+	var attempts int32
+	var mu sync.Mutex
+	var lengths []int64
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lengths = append(lengths, r.ContentLength)
+		mu.Unlock()
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			hijackClose(w)
+			return
+		}
+		io.Copy(w, r.Body)
+	}).SetRetryMax(3).SetBackoff(nil)
+
+	var readers []*trackedBody
+	provider := func() (io.ReadCloser, int64, error) {
+		b := &trackedBody{Reader: strings.NewReader(body)}
+		readers = append(readers, b)
+		return b, int64(len(body)), nil
+	}
+	resp, err := c.R().SetMethod(http.MethodPut).SetBodyProvider(provider).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != body {
+		t.Errorf("server received %q, want %q", got, body)
+	}
+	if len(readers) != 3 {
+		t.Fatalf("provider called %d times, want once per attempt (3)", len(readers))
+	}
+	for i, b := range readers {
+		if atomic.LoadInt32(&b.closed) == 0 {
+			t.Errorf("reader %d was not closed", i)
+		}
+	}
+	for i, n := range lengths {
+		if n != int64(len(body)) {
+			t.Errorf("attempt %d Content-Length %d, want %d", i+1, n, len(body))
+		}
+	}
+}
+
+func TestSetBodyProviderError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request sent although the provider failed")
+	})
+	errProvider := errors.New("cannot reopen body")
+	_, err := c.R().SetMethod(http.MethodPut).SetBodyProvider(func() (io.ReadCloser, int64, error) {
+		return nil, 0, errProvider
+	}).Execute()
+	if !errors.Is(err, errProvider) {
+		t.Errorf("got error %v, want %v", err, errProvider)
+	}
+}
+
+func TestSetBodyJSONScalars(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	for _, body := range []string{"42", "-1.5e3", `"text"`, "true", "null", `{"a":1}`, `[1,2]`} {
+		resp, err := c.R().SetMethod(http.MethodPost).SetBodyJSON(body).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != body {
+			t.Errorf("SetBodyJSON(%q) sent %q", body, got)
+		}
+	}
+
+	logger := &captureLogger{}
+	c.Logger = logger
+	resp, err := c.R().SetMethod(http.MethodPost).SetBodyJSON("{not json").Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "" {
+		t.Errorf("invalid JSON string was sent: %q", got)
+	}
+	if !strings.Contains(logger.String(), "invalid JSON string") {
+		t.Errorf("invalid JSON string was not logged: %s", logger.String())
+	}
+}
+
+func TestSetBodyJSONRaw(t *testing.T) {
+	var contentType string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		io.Copy(w, r.Body)
+	})
+	for _, body := range []string{"42", "{not validated"} {
+		resp, err := c.R().SetMethod(http.MethodPost).SetBodyJSONRaw(body).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.String(); got != body {
+			t.Errorf("SetBodyJSONRaw(%q) sent %q", body, got)
+		}
+		if !strings.HasPrefix(contentType, ContentTypeJson) {
+			t.Errorf("Content-Type %q, want %s", contentType, ContentTypeJson)
+		}
+	}
+}
+
+func TestSetQueryParamTyped(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RawQuery)
+	})
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"int", c.R().SetQueryParamInt("n", -42), "n=-42"},
+		{"bool true", c.R().SetQueryParamBool("b", true), "b=true"},
+		{"bool false", c.R().SetQueryParamBool("b", false), "b=false"},
+		{"float fraction", c.R().SetQueryParamFloat("f", 0.5), "f=0.5"},
+		{"float large", c.R().SetQueryParamFloat("f", 1200000), "f=1200000"},
+		{"float small", c.R().SetQueryParamFloat("f", 0.000125), "f=0.000125"},
+		{"float integral", c.R().SetQueryParamFloat("f", 3), "f=3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("query %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRawPath(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RequestURI)
+	})
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"encoded slash", c.R().SetRawPath("/files/a%2Fb/c"), "/files/a%2Fb/c"},
+		{"lowercase escape", c.R().SetRawPath("/files/a%2fb"), "/files/a%2fb"},
+		{"unnormalized", c.R().SetRawPath("/a/./b/../c"), "/a/./b/../c"},
+		{"with query", c.R().SetRawPath("/repos/o%2Fr").SetQueryParam("page", "2"), "/repos/o%2Fr?page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetHeadersAndQueryParamsSlice(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("X-A"), r.Header.Get("X-B"), r.URL.RawQuery)
+	})
+	resp, err := c.R().
+		SetHeadersSlice([]KV{{"X-A", "1"}, {"X-B", "2"}, {"x-a", "3"}}).
+		SetQueryParamsSlice([]KV{{"q", "go"}, {"page", "1"}, {"q", "rust"}}).
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.String(), "3|2|page=1&q=rust"; got != want {
+		t.Errorf("got %q, want %q with later pairs winning", got, want)
+	}
+}
+
+func BenchmarkSetHeaders(b *testing.B) {
+	c := NewClient()
+	b.Run("map", func(b *testing.B) {
+		r := c.R()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.SetHeaders(map[string]string{"X-A": "1", "X-B": "2", "X-C": "3", "X-D": "4"})
+			r.SetQueryParams(map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"})
+		}
+	})
+	b.Run("slice", func(b *testing.B) {
+		r := c.R()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.SetHeadersSlice([]KV{{"X-A", "1"}, {"X-B", "2"}, {"X-C", "3"}, {"X-D", "4"}})
+			r.SetQueryParamsSlice([]KV{{"a", "1"}, {"b", "2"}, {"c", "3"}, {"d", "4"}})
+		}
+	})
+}
+
+func TestConditionalRequestHeaders(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-If-Modified-Since", r.Header.Get("If-Modified-Since"))
+		w.Header().Set("X-If-None-Match", r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", modified, strings.NewReader("content"))
+	})
+
+	tests := []struct {
+		name         string
+		req          *Request
+		header, want string
+		notModified  bool
+	}{
+		{"unconditional", c.R(), "X-If-None-Match", "", false},
+		{"modified since", c.R().SetIfModifiedSince(modified.Add(-time.Hour)), "X-If-Modified-Since", "Fri, 01 Mar 2024 11:00:00 GMT", false},
+		{"not modified since", c.R().SetIfModifiedSince(modified.In(time.FixedZone("CST", 8*3600))), "X-If-Modified-Since", "Fri, 01 Mar 2024 12:00:00 GMT", true},
+		{"etag quoted", c.R().SetIfNoneMatch("v1"), "X-If-None-Match", `"v1"`, true},
+		{"etag already quoted", c.R().SetIfNoneMatch(`"v0"`), "X-If-None-Match", `"v0"`, false},
+		{"weak etag", c.R().SetIfNoneMatch(`W/"v1"`), "X-If-None-Match", `W/"v1"`, true},
+		{"any", c.R().SetIfNoneMatch("*"), "X-If-None-Match", "*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.req.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.GetHeader(tt.header); got != tt.want {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+			if got := resp.IsNotModified(); got != tt.notModified {
+				t.Errorf("IsNotModified() = %v with status %d, want %v", got, resp.StatusCode(), tt.notModified)
+			}
+		})
+	}
+}
+
+func TestSetBodyAny(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", mediaType, body)
+	})
+	tests := []struct {
+		name string
+		body interface{}
+		want string
+	}{
+		{"string", "plain text", ContentTypeText + "|plain text"},
+		{"bytes", []byte{'r', 'a', 'w'}, ContentTypeStream + "|raw"},
+		{"read seeker", strings.NewReader("seekable"), ContentTypeStream + "|seekable"},
+		{"reader", io.MultiReader(strings.NewReader("multi"), bytes.NewBufferString("reader")), ContentTypeStream + "|multireader"},
+		{"form", url.Values{"a": {"1"}, "b": {"x y"}}, ContentTypeForm + "|a=1&b=x+y"},
+		{"struct", struct {
+			Name string `json:"name"`
+		}{"n"}, ContentTypeJson + `|{"name":"n"}`},
+		{"map", map[string]int{"a": 1}, ContentTypeJson + `|{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.R().SetMethod(http.MethodPost).SetBodyAny(tt.body).Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestWhen(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization"))
+	})
+	for _, prod := range []bool{true, false} {
+		calls := 0
+		resp, err := c.R().When(prod, func(r *Request) {
+			calls++
+			r.SetHeader("Authorization", "Bearer prod")
+		}).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, wantCalls := "", 0
+		if prod {
+			want, wantCalls = "Bearer prod", 1
+		}
+		if got := resp.String(); got != want || calls != wantCalls {
+			t.Errorf("When(%v): Authorization %q after %d calls, want %q after %d", prod, got, calls, want, wantCalls)
+		}
+	}
+}
+
+func TestSetBodyChannel(t *testing.T) {
+	var chunked bool
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		chunked = len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+		io.Copy(w, r.Body)
+	})
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 5; i++ {
+			ch <- []byte(fmt.Sprintf("chunk-%d;", i))
+		}
+	}()
+	resp, err := c.R().SetMethod(http.MethodPost).SetBodyChannel(ch).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.String(), "chunk-0;chunk-1;chunk-2;chunk-3;chunk-4;"; got != want {
+		t.Errorf("server received %q, want %q", got, want)
+	}
+	if !chunked {
+		t.Error("channel body was not sent with chunked transfer encoding")
+	}
+}
+
+func TestSetBodyChannelNotRetried(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		hijackClose(w)
+	}).SetRetryMax(3).SetBackoff(nil)
+	ch := make(chan []byte, 1)
+	ch <- []byte("once")
+	close(ch)
+	if _, err := c.R().SetMethod(http.MethodPost).SetBodyChannel(ch).Execute(); err == nil {
+		t.Fatal("request to a failing server succeeded")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("got %d attempts, want a channel body sent only once", n)
+	}
+}
+
+func TestSetBodyChannelContentMD5(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request with an unreplayable Content-MD5 body was sent")
+	})
+	ch := make(chan []byte)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		// 请求放弃后发送方不应被阻塞
+		for i := 0; i < 3; i++ {
+			ch <- []byte("data")
+		}
+		close(ch)
+	}()
+	_, err := c.R().SetMethod(http.MethodPost).SetBodyChannel(ch).SetContentMD5().Execute()
+	if err == nil || !strings.Contains(err.Error(), "replayable") {
+		t.Errorf("got error %v, want a replayable body error", err)
+	}
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("channel producer blocked after the request was abandoned")
+	}
+}
+
+func TestSetQueryParamsFromURL(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Query().Encode())
+	})
+	resp, err := c.R().SetQueryParam("keep", "1").
+		SetQueryParamsFromURL("https://example.com/list?page=2&tag=go&tag=http&q=a+b%26c").
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := url.ParseQuery(resp.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"keep": {"1"}, "page": {"2"}, "tag": {"go", "http"}, "q": {"a b&c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("server received %v, want %v", got, want)
+	}
+
+	logger := &captureLogger{}
+	c.Logger = logger
+	c.R().SetQueryParamsFromURL("http://[::1")
+	if !strings.Contains(logger.String(), "invalid URL") {
+		t.Errorf("invalid URL was not logged: %s", logger.String())
+	}
+}
@@ -2,24 +2,58 @@ package quicklyHttps
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/tidwall/gjson"
+	"hash"
 	"io"
+	"mime"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 )
 
+// ErrTruncatedBody 表示读取到的响应体比 Content-Length 声明的短
+var ErrTruncatedBody = errors.New("response body truncated")
+
+// ErrBodyConsumed 表示响应体已通过 Stream、RawBody 等方式以流的形式读取，无法再次读取
+var ErrBodyConsumed = errors.New("body already consumed")
+
+// ValidationError 表示响应未通过客户端设置的校验函数，此类错误不会触发重试
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("response validation failed: %v", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // Response 封装了 HTTP 响应，提供了便捷的方法来处理响应。
 type Response struct {
 	*http.Response
 	Err             error
 	body            []byte
+	buf             *bytes.Buffer
 	bodyMutex       sync.Mutex
+	consumed        bool
+	decoded         bool
 	rawRequest      *Request
 	jsonMarshaler   func(v any) ([]byte, error)
 	jsonUnmarshaler func(data []byte, v any) error
@@ -28,24 +62,79 @@ type Response struct {
 	result          interface{}
 }
 
-// Body 返回响应体的字节数组。
+// Body 返回响应体的字节数组，响应体已被流式读取时返回 nil 并将 Err 设置为 ErrBodyConsumed。
 func (r *Response) Body() []byte {
 	if r.Response == nil {
 		return nil
 	}
 	r.bodyMutex.Lock()
 	defer r.bodyMutex.Unlock()
+	if r.body == nil && r.skipHeadBody() {
+		_ = r.Response.Body.Close()
+		r.body = []byte{}
+	}
+	if r.body == nil && r.consumed {
+		r.Err = ErrBodyConsumed
+		return nil
+	}
 	if r.body == nil && r.Response.Body != nil {
-		var err error
-		r.body, err = readBody(r.Response.Body)
+		buf, err := readBodyBuffer(r.Response.Body)
 		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("%w: %v", ErrTruncatedBody, err)
+			}
 			r.Err = err
 			return nil
 		}
+		r.buf = buf
+		r.body = buf.Bytes()
+		r.checkContentLength()
 	}
 	return r.body
 }
 
+// skipHeadBody 判断是否为需要忽略响应体的 HEAD 请求的响应
+func (r *Response) skipHeadBody() bool {
+	return r.Response.Body != nil && r.Response.Request != nil && r.Response.Request.Method == http.MethodHead &&
+		r.rawRequest != nil && !r.rawRequest.rawClient.ReadHeadBody
+}
+
+// checkContentLength 检查读取到的响应体长度是否与 Content-Length 一致，不一致时设置 Err
+func (r *Response) checkContentLength() {
+	expected := r.Response.ContentLength
+	if expected < 0 || r.Response.Uncompressed {
+		return
+	}
+	if r.Response.Request != nil && r.Response.Request.Method == http.MethodHead {
+		return
+	}
+	if r.Response.StatusCode == http.StatusNoContent || r.Response.StatusCode == http.StatusNotModified {
+		return
+	}
+	if int64(len(r.body)) != expected {
+		r.Err = fmt.Errorf("%w: expected %d bytes, got %d", ErrTruncatedBody, expected, len(r.body))
+	}
+}
+
+// isTruncated 读取响应体并判断是否被截断
+func (r *Response) isTruncated() bool {
+	r.Body()
+	return errors.Is(r.Err, ErrTruncatedBody)
+}
+
+// share 为合并请求的其它调用方创建共享同一响应和已缓存响应体的副本。
+func (r *Response) share(req *Request) *Response {
+	return &Response{
+		Response:        r.Response,
+		Err:             r.Err,
+		body:            r.body,
+		rawRequest:      req,
+		jsonMarshaler:   r.jsonMarshaler,
+		jsonUnmarshaler: r.jsonUnmarshaler,
+		receivedAt:      r.receivedAt,
+	}
+}
+
 // String 返回响应体的字符串表示。
 func (r *Response) String() string {
 	body := r.Body()
@@ -55,14 +144,164 @@ func (r *Response) String() string {
 	return string(body)
 }
 
-// readBody 读取并返回响应体。
-func readBody(body io.ReadCloser) ([]byte, error) {
+// maxPooledBufferSize 超过该容量的缓冲区不放回缓冲池，避免长期占用大块内存
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// readBodyBuffer 使用缓冲池中的缓冲区读取响应体，读取失败时缓冲区会被归还。
+func readBodyBuffer(body io.ReadCloser) (*bytes.Buffer, error) {
 	defer body.Close()
-	content, err := io.ReadAll(body)
-	if err != nil {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if _, err := buf.ReadFrom(body); err != nil {
+		putBuffer(buf)
 		return nil, err
 	}
-	return content, nil
+	return buf, nil
+}
+
+// putBuffer 将缓冲区归还到缓冲池
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// Release 将响应体使用的缓冲区归还到缓冲池，以减少高并发场景下的 GC 压力。
+// 调用后不得再使用此前通过 Body()、String() 等方法得到的响应体数据，Body() 将返回 nil。
+func (r *Response) Release() {
+	if r == nil {
+		return
+	}
+	r.bodyMutex.Lock()
+	defer r.bodyMutex.Unlock()
+	if r.buf != nil {
+		putBuffer(r.buf)
+		r.buf = nil
+		// 响应体已在 Body() 中读取并关闭
+		r.Response.Body = http.NoBody
+	}
+	r.body = nil
+	r.consumed = true
+}
+
+// Stream 返回用于流式读取的响应体，调用方负责关闭。响应体已被 Body() 缓存时返回基于缓存的 Reader，
+// 已通过 Stream 或 RawBody 取走时返回 ErrBodyConsumed。调用后 Body()、SaveToFile 同样返回 ErrBodyConsumed
+func (r *Response) Stream() (io.ReadCloser, error) {
+	r.bodyMutex.Lock()
+	defer r.bodyMutex.Unlock()
+	if r.body != nil {
+		return io.NopCloser(bytes.NewReader(r.body)), nil
+	}
+	if r.consumed {
+		return nil, ErrBodyConsumed
+	}
+	if r.Response == nil || r.Response.Body == nil {
+		return http.NoBody, nil
+	}
+	r.consumed = true
+	return r.Response.Body, nil
+}
+
+// RawBody 返回底层未缓存的响应体，用于手动流式读取，调用方负责关闭。
+// 调用后 Body() 不再读取和缓存响应体；若响应体已被 Body() 缓存，则返回基于缓存的 Reader。
+func (r *Response) RawBody() io.ReadCloser {
+	r.bodyMutex.Lock()
+	defer r.bodyMutex.Unlock()
+	if r.body != nil {
+		return io.NopCloser(bytes.NewReader(r.body))
+	}
+	if r.Response == nil || r.Response.Body == nil {
+		return http.NoBody
+	}
+	r.consumed = true
+	return r.Response.Body
+}
+
+// discard 读取并丢弃响应体后关闭，使连接可以尽快复用，之后 Body() 返回空内容。
+func (r *Response) discard() {
+	if r.Response.Body != nil {
+		_, _ = io.Copy(io.Discard, r.Response.Body)
+		_ = r.Response.Body.Close()
+	}
+	r.Response.Body = http.NoBody
+	r.body = []byte{}
+}
+
+// Drain 读取并丢弃尚未读取的响应体后关闭，使底层连接可以被复用。
+// 通过 RawBody 等方式只读取了部分响应体时，应在读取结束后调用 Drain。
+// 响应体已通过 Body() 缓存、已调用过 Drain 或 Release 时不做任何处理
+func (r *Response) Drain() error {
+	if r.Response == nil || r.Response.Body == nil {
+		return nil
+	}
+	r.bodyMutex.Lock()
+	defer r.bodyMutex.Unlock()
+	if r.body != nil {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, r.Response.Body)
+	if closeErr := r.Response.Body.Close(); err == nil {
+		err = closeErr
+	}
+	r.Response.Body = http.NoBody
+	r.consumed = true
+	return err
+}
+
+// BodyReader 返回基于缓存响应体的新 Reader，每次调用互不影响，可供多个使用方分别读取。
+func (r *Response) BodyReader() io.Reader {
+	return bytes.NewReader(r.Body())
+}
+
+// TeeBody 返回一个读取响应体的 Reader，读取的同时将内容写入 w，读取结束后响应体会被关闭。
+// 响应体不会被缓存，适合一边转发一边记录或计算摘要的场景。与 Stream 相同，调用后 Body()、SaveToFile 返回 ErrBodyConsumed；
+// 响应体已通过 Stream 或 RawBody 取走时，返回的 Reader 读取时返回 ErrBodyConsumed
+func (r *Response) TeeBody(w io.Writer) io.Reader {
+	if r.Response == nil {
+		return bytes.NewReader(nil)
+	}
+	body, err := r.Stream()
+	if err != nil {
+		return errorReader{err: err}
+	}
+	return &teeBody{Reader: io.TeeReader(body, w), body: body}
+}
+
+// teeBody 在读取结束或出错时关闭响应体
+type teeBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.Reader.Read(p)
+	if err != nil {
+		t.body.Close()
+	}
+	return n, err
+}
+
+// errorReader 每次读取都返回 err
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+// Raw 返回底层的 *http.Response，便于访问被 Header()、StatusCode() 等方法遮蔽的字段，
+// 如 TLS、Proto、Request，请求失败时可能为 nil
+func (r *Response) Raw() *http.Response {
+	return r.Response
 }
 
 // StatusCode 返回响应的状态码。
@@ -86,6 +325,55 @@ func (r *Response) JSON(v interface{}) error {
 	return r.jsonUnmarshaler(r.Body(), v)
 }
 
+// JSONGzip 先对响应体进行 gzip 解压再解析为 JSON，用于应用层压缩的响应体，
+// 与传输层的 Content-Encoding: gzip 不同，后者由 Transport 自动解压
+func (r *Response) JSONGzip(v interface{}) error {
+	reader, err := gzip.NewReader(bytes.NewReader(r.Body()))
+	if err != nil {
+		return fmt.Errorf("failed to decompress gzip body: %w", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to decompress gzip body: %w", err)
+	}
+	return r.jsonUnmarshaler(data, v)
+}
+
+// JSONP 去除形如 callback({...}); 的 JSONP 回调包装后将响应体解析为 JSON，未包装的响应体按普通 JSON 解析。
+func (r *Response) JSONP(v interface{}) error {
+	return r.jsonUnmarshaler(stripJSONP(r.Body()), v)
+}
+
+// IsContentType 检查响应的媒体类型是否与 mediaType 完全一致，忽略参数和大小写。
+func (r *Response) IsContentType(mediaType string) bool {
+	actual, _, err := mime.ParseMediaType(r.GetHeader("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(actual, strings.TrimSpace(mediaType))
+}
+
+// IsValidJSON 检查响应体是否为合法的 JSON。
+func (r *Response) IsValidJSON() bool {
+	return json.Valid(r.Body())
+}
+
+// SniffContentType 返回响应的内容类型，响应头缺失或为通用的二进制类型时根据响应体前 512 字节推断。
+func (r *Response) SniffContentType() string {
+	contentType := r.GetHeader("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType != ContentTypeStream {
+		return contentType
+	}
+	return http.DetectContentType(r.Body())
+}
+
+// IsNotModified 检查响应是否为 304 Not Modified，用于配合条件请求判断资源是否变化。
+func (r *Response) IsNotModified() bool {
+	return r.StatusCode() == http.StatusNotModified
+}
+
 // IsSuccess 检查响应是否表示成功的请求。
 func (r *Response) IsSuccess() bool {
 	return r.StatusCode() >= 200 && r.StatusCode() < 300
@@ -101,16 +389,34 @@ func (r *Response) IsServerError() bool {
 	return r.StatusCode() >= 500 && r.StatusCode() < 600
 }
 
-// SaveToFile 将响应体保存到指定文件。
+// IsError 检查响应是否表示客户端或服务器错误。
+func (r *Response) IsError() bool {
+	return r.StatusCode() >= 400
+}
+
+// OnSuccess 在响应为 2xx 时调用 fn，返回响应本身以便继续链式调用。
+func (r *Response) OnSuccess(fn func(*Response)) *Response {
+	if r != nil && r.IsSuccess() {
+		fn(r)
+	}
+	return r
+}
+
+// OnError 在响应为 4xx 或 5xx 时调用 fn，返回响应本身以便继续链式调用。
+func (r *Response) OnError(fn func(*Response)) *Response {
+	if r != nil && r.IsError() {
+		fn(r)
+	}
+	return r
+}
+
+// SaveToFile 将响应体保存到指定文件，响应体已被流式读取时返回 ErrBodyConsumed。
 func (r *Response) SaveToFile(filepath string) error {
-	if r.body == nil {
-		var err error
-		r.body, err = readBody(r.Response.Body)
-		if err != nil {
-			return err
-		}
+	body := r.Body()
+	if body == nil && r.Err != nil {
+		return r.Err
 	}
-	return os.WriteFile(filepath, r.body, 0644)
+	return os.WriteFile(filepath, body, 0644)
 }
 
 // ToBytesBuffer 返回响应体的字节缓冲区。
@@ -125,20 +431,43 @@ func (r *Response) ToMap() (map[string]interface{}, error) {
 	return result, err
 }
 
+// SHA256 返回响应体的十六进制 SHA-256 摘要
+func (r *Response) SHA256() string {
+	sum := sha256.Sum256(r.Body())
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyChecksum 使用指定算法（md5、sha1、sha256、sha512）校验响应体的十六进制摘要
+func (r *Response) VerifyChecksum(algo, expected string) error {
+	var h hash.Hash
+	switch strings.ToLower(algo) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+	h.Write(r.Body())
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("%s checksum mismatch: expected %s, got %s", algo, expected, actual)
+	}
+	return nil
+}
+
 // logResponse 记录响应信息
 func (r *Response) logResponse() {
 	logger := r.rawRequest.rawClient.logger()
 
 	// 将 headers 和 cookies 转换为更易读的格式
-	headers := make(map[string]string)
-	for key, values := range r.Header() {
-		headers[key] = strings.Join(values, ", ")
-	}
-
-	cookies := make([]string, len(r.Cookies()))
-	for i, cookie := range r.Cookies() {
-		cookies[i] = fmt.Sprintf("%s=%s", cookie.Name, cookie.Value)
-	}
+	client := r.rawRequest.rawClient
+	headers := client.logHeaders(r.Header())
+	cookies := client.logCookies(r.Cookies(), "Set-Cookie")
 
 	// 创建日志消息
 	logMessage := map[string]interface{}{
@@ -146,61 +475,279 @@ func (r *Response) logResponse() {
 		"status":      r.Status,
 		"headers":     headers,
 		"cookies":     cookies,
-		"body":        r.String(),
+		"body":        r.logBody(),
 	}
 
 	// 记录日志
 	logger.Info("Received response", logMessage)
 }
 
-// DetectEncoding 检测响应体的编码并转换为 UTF-8
+// logBody 返回用于日志的响应体，流式模式下不读取响应体
+func (r *Response) logBody() string {
+	client := r.rawRequest.rawClient
+	if r.rawRequest.streaming() {
+		return "(stream mode, body not logged)"
+	}
+	return truncateBody(r.String(), client.MaxBodyLogSize)
+}
+
+// DetectEncoding 检测响应体的编码并转换为 UTF-8。
+// 优先使用 Content-Type 声明的字符集，未声明且内容不是合法的 UTF-8 时使用客户端设置的回退字符集（默认 GBK）。
+// 多次调用只会转换一次
 func (r *Response) DetectEncoding() error {
-	r.bodyMutex.Lock()
-	defer r.bodyMutex.Unlock()
+	if r.decoded {
+		return nil
+	}
 	body := r.Body()
-	if !utf8.Valid(body) {
-		// 假设响应体是 GBK 编码，进行转换
-		decodedBody, err := ConvertGBKToUTF8(body)
-		if err != nil {
-			return fmt.Errorf("failed to convert body to UTF-8: %w", err)
+	charset := r.declaredCharset()
+	if charset == "" {
+		if utf8.Valid(body) {
+			return nil
 		}
-		r.body = decodedBody
+		charset = r.rawRequest.rawClient.ResponseCharsetFallback
+	}
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "utf8") {
+		return nil
+	}
+	decodedBody, err := decodeCharset(body, charset)
+	if err != nil {
+		return fmt.Errorf("failed to convert body to UTF-8: %w", err)
 	}
+	r.bodyMutex.Lock()
+	r.body = decodedBody
+	r.decoded = true
+	r.bodyMutex.Unlock()
 	return nil
 }
 
+// declaredCharset 返回 Content-Type 中声明的字符集
+func (r *Response) declaredCharset() string {
+	_, params, err := mime.ParseMediaType(r.GetHeader("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
 // Gjson 解析响应体为 gjson.Result
 func (r *Response) Gjson() gjson.Result {
 	return gjson.ParseBytes(r.Body())
 }
 
+// ScanMany 按 gjson 路径从响应体中提取多个字段，mapping 的键为路径，值为接收结果的指针，
+// 路径不存在或解析失败时继续处理其余路径，最后返回合并后的错误
+func (r *Response) ScanMany(mapping map[string]interface{}) error {
+	paths := make([]string, 0, len(mapping))
+	for path := range mapping {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	results := gjson.GetManyBytes(r.Body(), paths...)
+	var errs []error
+	for i, path := range paths {
+		if !results[i].Exists() {
+			errs = append(errs, fmt.Errorf("path %q not found", path))
+			continue
+		}
+		if err := r.jsonUnmarshaler([]byte(results[i].Raw), mapping[path]); err != nil {
+			errs = append(errs, fmt.Errorf("path %q: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // GetCookies 获取响应的 Cookies
 func (r *Response) GetCookies() []*http.Cookie {
 	return r.Cookies()
 }
 
+// SyncCookiesToJar 将响应的 Set-Cookie 存入发起请求的客户端的 CookieJar，作用域为请求的 URL，
+// 之后同一客户端发出的请求会自动携带这些 Cookie
+func (r *Response) SyncCookiesToJar() error {
+	if r.Response == nil || r.Response.Request == nil {
+		return errors.New("response has no originating request")
+	}
+	jar := r.rawRequest.rawClient.Client.Jar
+	if jar == nil {
+		return errors.New("cookie jar is not enabled")
+	}
+	if cookies := r.Cookies(); len(cookies) > 0 {
+		jar.SetCookies(r.Response.Request.URL, cookies)
+	}
+	return nil
+}
+
 // GetHeader 获取指定的响应头信息
 func (r *Response) GetHeader(key string) string {
 	return r.Header().Get(key)
 }
 
+// GetHeaderTime 解析日期类型的响应头（如 Date、Expires、Last-Modified），支持 HTTP 允许的各种日期格式
+func (r *Response) GetHeaderTime(key string) (time.Time, error) {
+	value := r.GetHeader(key)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("response header %s not found", key)
+	}
+	return http.ParseTime(value)
+}
+
+// Age 返回响应的当前年龄，即响应在缓存中已存在的时间。
+// 根据 Age 和 Date 响应头计算初始年龄，再加上收到响应后经过的时间，两个响应头都不存在时返回错误
+func (r *Response) Age() (time.Duration, error) {
+	var initial time.Duration
+	found := false
+	if value := r.GetHeader("Age"); value != "" {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || seconds < 0 {
+			return 0, fmt.Errorf("invalid Age header %q", value)
+		}
+		initial = time.Duration(seconds) * time.Second
+		found = true
+	}
+	if date, err := r.GetHeaderTime("Date"); err == nil {
+		if apparent := r.receivedAt.Sub(date); apparent > initial {
+			initial = apparent
+		}
+		found = true
+	}
+	if !found {
+		return 0, errors.New("response has neither Age nor Date header")
+	}
+	return initial + now().Sub(r.receivedAt), nil
+}
+
+// IsStale 判断响应是否已过期需要重新验证。有效期取 Cache-Control 的 max-age，
+// 未声明时使用 maxAge；Cache-Control 含 no-cache 或无法确定响应年龄时视为已过期
+func (r *Response) IsStale(maxAge time.Duration) bool {
+	for _, directive := range strings.Split(r.GetHeader("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache":
+			return true
+		case "max-age":
+			if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	age, err := r.Age()
+	if err != nil {
+		return true
+	}
+	return age >= maxAge
+}
+
 // HasHeader 检查指定的响应头是否存在
 func (r *Response) HasHeader(key string) bool {
 	_, ok := r.Header()[key]
 	return ok
 }
 
+// RequireHeaders 检查响应是否包含全部指定的响应头，缺失时返回列出缺失项的错误
+func (r *Response) RequireHeaders(keys ...string) error {
+	header := r.Header()
+	var missing []string
+	for _, key := range keys {
+		key = http.CanonicalHeaderKey(key)
+		if _, ok := header[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required response headers: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// HeaderMatches 检查指定响应头的值是否匹配正则表达式，每次调用都会重新编译，
+// 需要反复匹配同一表达式时可自行编译后使用 GetHeader
+func (r *Response) HeaderMatches(key, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(r.GetHeader(key)), nil
+}
+
 // GetHeaderValues 获取指定的响应头的所有值
 func (r *Response) GetHeaderValues(key string) []string {
 	return r.Header()[key]
 }
 
-// PrettyPrint 以易读的格式打印响应体
+// ForEachHeader 按头部名称的字典序遍历响应头，对每个头部调用 fn，fn 不应修改 values
+func (r *Response) ForEachHeader(fn func(key string, values []string)) {
+	header := r.Header()
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(key, header[key])
+	}
+}
+
+// HeaderCount 返回响应头的数量，同名的多值头部只计一次
+func (r *Response) HeaderCount() int {
+	return len(r.Header())
+}
+
+// hopByHopHeaders 逐跳头部，只对单个连接有意义，代理转发时不应传递，见 RFC 7230 第 6.1 节
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// CopyHeadersTo 将响应头复制到 dst，多值头部会逐个追加，用于在反向代理中转发上游响应。
+// 逐跳头部以及 Connection 头部中列出的头部不会被复制。
+func (r *Response) CopyHeadersTo(dst http.Header) {
+	header := r.Header()
+	skip := make(map[string]bool, len(hopByHopHeaders))
+	for _, key := range hopByHopHeaders {
+		skip[key] = true
+	}
+	for _, value := range header.Values("Connection") {
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				skip[http.CanonicalHeaderKey(key)] = true
+			}
+		}
+	}
+	for key, values := range header {
+		if skip[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// PrettyPrint 以易读的格式打印响应体，根据 Content-Type 格式化 JSON、XML 和 HTML，
+// 无法格式化时返回原始内容
 func (r *Response) PrettyPrint() string {
-	var prettyJSON bytes.Buffer
-	err := json.Indent(&prettyJSON, r.Body(), "", "  ")
+	mediaType, _, _ := mime.ParseMediaType(r.GetHeader("Content-Type"))
+	var pretty string
+	var err error
+	switch {
+	case mediaType == ContentTypeHtml:
+		pretty, err = indentHTML(r.Body())
+	case strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml"):
+		pretty, err = indentXML(r.Body())
+	default:
+		var prettyJSON bytes.Buffer
+		err = json.Indent(&prettyJSON, r.Body(), "", "  ")
+		pretty = prettyJSON.String()
+	}
 	if err != nil {
 		return r.String()
 	}
-	return prettyJSON.String()
+	return pretty
 }
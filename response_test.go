@@ -0,0 +1,1087 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseTeeBody(t *testing.T) {
+	payload := strings.Repeat("stream-and-capture ", 1024)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var captured, forwarded bytes.Buffer
+	if _, err := io.Copy(&forwarded, resp.TeeBody(&captured)); err != nil {
+		t.Fatal(err)
+	}
+	if forwarded.String() != payload {
+		t.Errorf("forwarded copy mismatch: got %d bytes, want %d", forwarded.Len(), len(payload))
+	}
+	if captured.String() != payload {
+		t.Errorf("captured copy mismatch: got %d bytes, want %d", captured.Len(), len(payload))
+	}
+}
+
+func TestResponseRequireHeaders(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-Request-Id", "abc")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.RequireHeaders("x-ratelimit-remaining", "X-REQUEST-ID"); err != nil {
+		t.Errorf("present headers reported missing: %v", err)
+	}
+	err = resp.RequireHeaders("X-RateLimit-Remaining", "x-ratelimit-reset", "ETag")
+	if err == nil {
+		t.Fatal("expected error for missing headers")
+	}
+	for _, key := range []string{"X-Ratelimit-Reset", "Etag"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not list %s", err, key)
+		}
+	}
+	if strings.Contains(err.Error(), "X-Ratelimit-Remaining") {
+		t.Errorf("error %q lists a present header", err)
+	}
+}
+
+func TestResponseBodyReader(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "shared body")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := resp.BodyReader(), resp.BodyReader()
+	for i, reader := range []io.Reader{first, second} {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "shared body" {
+			t.Errorf("reader %d yielded %q", i, data)
+		}
+	}
+	if resp.String() != "shared body" {
+		t.Errorf("cached body changed to %q", resp.String())
+	}
+}
+
+func TestResponseSHA256AndVerifyChecksum(t *testing.T) {
+	const payload = "release-1.2.3.tar.gz contents"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sha := sha256.Sum256([]byte(payload))
+	wantSHA := hex.EncodeToString(sha[:])
+	if got := resp.SHA256(); got != wantSHA {
+		t.Errorf("SHA256() = %s, want %s", got, wantSHA)
+	}
+	sum := md5.Sum([]byte(payload))
+	wantMD5 := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		algo, expected string
+		wantErr        bool
+	}{
+		{"sha256", wantSHA, false},
+		{"SHA256", strings.ToUpper(wantSHA) + "\n", false},
+		{"md5", wantMD5, false},
+		{"sha256", wantMD5, true},
+		{"md5", strings.Repeat("0", 32), true},
+		{"crc32", "00000000", true},
+	}
+	for _, tt := range tests {
+		err := resp.VerifyChecksum(tt.algo, tt.expected)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("VerifyChecksum(%q, %q) error = %v, wantErr %v", tt.algo, tt.expected, err, tt.wantErr)
+		}
+	}
+	if string(resp.Body()) != payload {
+		t.Errorf("body changed after hashing: %q", resp.Body())
+	}
+}
+
+func TestResponseIsValidJSON(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"ok":true,"items":[1,2]}`, true},
+		{`[]`, true},
+		{`{"ok":true`, false},
+		{`<html></html>`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, tt.body)
+		})
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.IsValidJSON(); got != tt.want {
+			t.Errorf("IsValidJSON(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestResponseSniffContentType(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	tests := []struct {
+		name, contentType, body, want string
+	}{
+		{"png labelled as stream", ContentTypeStream, png, "image/png"},
+		{"html without header", "", "<!DOCTYPE html><html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"declared type kept", "application/json; charset=utf-8", "<html></html>", "application/json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType == "" {
+					// 阻止 net/http 服务端自动探测并写入 Content-Type
+					w.Header()["Content-Type"] = nil
+				} else {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				io.WriteString(w, tt.body)
+			})
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.SniffContentType(); got != tt.want {
+				t.Errorf("SniffContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// truncatingHandler 声明完整的 Content-Length，前 truncated 次请求只写出一半后断开连接
+func truncatingHandler(payload string, truncated int32, attempts *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(attempts, 1)
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		if n > truncated {
+			io.WriteString(w, payload)
+			return
+		}
+		io.WriteString(w, payload[:len(payload)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func TestResponseTruncatedBody(t *testing.T) {
+	payload := strings.Repeat("0123456789", 100)
+
+	t.Run("detected", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, truncatingHandler(payload, 1, &attempts))
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body := resp.Body(); len(body) == len(payload) {
+			t.Fatalf("got full body, want truncated")
+		}
+		if !errors.Is(resp.Err, ErrTruncatedBody) {
+			t.Errorf("Err = %v, want ErrTruncatedBody", resp.Err)
+		}
+		if n := atomic.LoadInt32(&attempts); n != 1 {
+			t.Errorf("server saw %d attempts, want no retry by default", n)
+		}
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		useFakeClock(t)
+		var attempts int32
+		c := newTestClient(t, truncatingHandler(payload, 1, &attempts)).SetRetryOnTruncatedBody(true).SetRetryMax(3)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(resp.Body()); got != payload {
+			t.Errorf("body length %d, want %d after retry", len(got), len(payload))
+		}
+		if resp.Err != nil {
+			t.Errorf("Err = %v after successful retry", resp.Err)
+		}
+		if n := atomic.LoadInt32(&attempts); n != 2 {
+			t.Errorf("server saw %d attempts, want 2", n)
+		}
+	})
+}
+
+func TestResponseOnSuccessOnError(t *testing.T) {
+	for _, tt := range []struct {
+		status           int
+		success, failure bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusCreated, true, false},
+		{http.StatusFound, false, false},
+		{http.StatusNotFound, false, true},
+		{http.StatusBadGateway, false, true},
+	} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}).SetRetryMax(1).SetCheckRedirect(func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse })
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var success, failure bool
+		chained := resp.
+			OnSuccess(func(*Response) { success = true }).
+			OnError(func(r *Response) { failure = r.StatusCode() == tt.status })
+		if chained != resp {
+			t.Error("handlers did not return the same response")
+		}
+		if success != tt.success || failure != tt.failure {
+			t.Errorf("status %d: OnSuccess fired %v, OnError fired %v; want %v, %v",
+				tt.status, success, failure, tt.success, tt.failure)
+		}
+	}
+
+	var nilResp *Response
+	nilResp.OnSuccess(func(*Response) { t.Error("OnSuccess fired on nil response") }).
+		OnError(func(*Response) { t.Error("OnError fired on nil response") })
+}
+
+func TestResponseRawBody(t *testing.T) {
+	payload := strings.Repeat("raw-stream ", 512)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	})
+
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := resp.RawBody()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := body.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if string(data) != payload {
+		t.Errorf("streamed %d bytes, want %d", len(data), len(payload))
+	}
+	if got := resp.Body(); got != nil || !errors.Is(resp.Err, ErrBodyConsumed) {
+		t.Errorf("Body() after RawBody = %q, Err %v; want nil and ErrBodyConsumed", got, resp.Err)
+	}
+
+	resp, err = c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := resp.Body()
+	data, _ = io.ReadAll(resp.RawBody())
+	if string(data) != payload || string(cached) != payload {
+		t.Errorf("RawBody after Body() returned %d bytes, want the cached %d", len(data), len(payload))
+	}
+}
+
+func TestResponseSyncCookiesToJar(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Path: "/"})
+			return
+		}
+		if cookie, err := r.Cookie("session"); err == nil {
+			io.WriteString(w, cookie.Value)
+		}
+	})
+	// 先禁用 Jar，使登录响应的 Cookie 不会被自动记录，之后由 SyncCookiesToJar 写入
+	c.Client.Jar = nil
+	resp, err := c.R().Execute("/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.SyncCookiesToJar(); err == nil {
+		t.Error("expected error without a cookie jar")
+	}
+
+	jar, _ := cookiejar.New(nil)
+	c.Client.Jar = jar
+	if err := resp.SyncCookiesToJar(); err != nil {
+		t.Fatal(err)
+	}
+	next, err := c.R().Execute("/profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next.String(); got != "s3cr3t" {
+		t.Errorf("next request carried session %q, want s3cr3t from the jar", got)
+	}
+}
+
+func TestSetUseJSONNumber(t *testing.T) {
+	const big = 9007199254740993 // 2^53 + 1，float64 无法精确表示
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"id":9007199254740993,"ratio":0.5,"nested":{"n":12345678901234567}}`)
+	})
+
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := resp.ToMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := m["id"].(float64); !ok || int64(f) == big {
+		t.Errorf("default id = %#v, want lossy float64", m["id"])
+	}
+
+	resp, err = c.SetUseJSONNumber(true).R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = resp.ToMap(); err != nil {
+		t.Fatal(err)
+	}
+	id, ok := m["id"].(json.Number)
+	if !ok {
+		t.Fatalf("id = %#v, want json.Number", m["id"])
+	}
+	if n, err := id.Int64(); err != nil || n != big {
+		t.Errorf("id = %v (%v), want %d", n, err, int64(big))
+	}
+	if nested := m["nested"].(map[string]interface{})["n"]; nested != json.Number("12345678901234567") {
+		t.Errorf("nested n = %#v, want json.Number", nested)
+	}
+	if ratio := m["ratio"]; ratio != json.Number("0.5") {
+		t.Errorf("ratio = %#v, want json.Number(0.5)", ratio)
+	}
+
+	var v struct{ ID interface{} }
+	if err := resp.JSON(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != json.Number("9007199254740993") {
+		t.Errorf("JSON decoded ID = %#v, want json.Number", v.ID)
+	}
+}
+
+func TestSetResponseCharsetFallback(t *testing.T) {
+	gbk := "\xd6\xd0\xce\xc4"  // "中文" 的 GBK 编码
+	big5 := "\xa4\xa4\xa4\xe5" // "中文" 的 Big5 编码
+	tests := []struct {
+		name, fallback, contentType, body, want string
+	}{
+		{"default GBK fallback", defaultResponseCharset, "text/plain", gbk, "中文"},
+		{"custom fallback", "big5", "text/plain", big5, "中文"},
+		{"valid UTF-8 not converted", "big5", "text/plain", "中文", "中文"},
+		{"declared charset wins", "big5", "text/plain; charset=gbk", gbk, "中文"},
+		{"no fallback", "", "text/plain", gbk, gbk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				io.WriteString(w, tt.body)
+			}).SetResponseCharsetFallback(tt.fallback)
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := resp.DetectEncoding(); err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseHeaderMatches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.example.v2+json; charset=utf-8")
+		w.Header().Set("X-Request-Id", "req-7f3a9c")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, pattern string
+		want         bool
+	}{
+		{"Content-Type", `^application/vnd\.example\.v\d+\+json`, true},
+		{"content-type", `v2\+json`, true},
+		{"Content-Type", `^application/json`, false},
+		{"X-Request-Id", `^req-[0-9a-f]{6}$`, true},
+		{"X-Request-Id", `^req-\d+$`, false},
+		{"X-Missing", `.+`, false},
+	}
+	for _, tt := range tests {
+		got, err := resp.HeaderMatches(tt.key, tt.pattern)
+		if err != nil {
+			t.Errorf("HeaderMatches(%q, %q) error: %v", tt.key, tt.pattern, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("HeaderMatches(%q, %q) = %v, want %v", tt.key, tt.pattern, got, tt.want)
+		}
+	}
+	if _, err := resp.HeaderMatches("Content-Type", `(unclosed`); err == nil {
+		t.Error("expected compile error for invalid pattern")
+	}
+}
+
+func TestResponseIsContentType(t *testing.T) {
+	tests := []struct {
+		header    string
+		mediaType string
+		want      bool
+	}{
+		{"application/json", "application/json", true},
+		{"application/json; charset=utf-8", "application/json", true},
+		{"Application/JSON; charset=UTF-8", "application/json", true},
+		{"application/json-patch+json", "application/json", false},
+		{"application/json", "application/json-patch+json", false},
+		{"text/html", "text/htm", false},
+		{"", "application/json", false},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.header)
+		})
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.IsContentType(tt.mediaType); got != tt.want {
+			t.Errorf("IsContentType(%q) with Content-Type %q = %v, want %v", tt.mediaType, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResponseGetHeaderTime(t *testing.T) {
+	want := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Sun, 06 Nov 1994 08:49:37 GMT")
+		w.Header().Set("Expires", "Sunday, 06-Nov-94 08:49:37 GMT")
+		w.Header().Set("X-Asctime", "Sun Nov  6 08:49:37 1994")
+		w.Header().Set("X-Invalid", "yesterday")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"Last-Modified", "Expires", "X-Asctime"} {
+		got, err := resp.GetHeaderTime(key)
+		if err != nil {
+			t.Errorf("GetHeaderTime(%s) error %v", key, err)
+		} else if !got.Equal(want) {
+			t.Errorf("GetHeaderTime(%s) = %v, want %v", key, got, want)
+		}
+	}
+	for _, key := range []string{"X-Invalid", "X-Missing"} {
+		if _, err := resp.GetHeaderTime(key); err == nil {
+			t.Errorf("GetHeaderTime(%s) returned no error", key)
+		}
+	}
+}
+
+func TestResponseRelease(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "pooled body")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "pooled body" {
+		t.Fatalf("body %q, want pooled body", got)
+	}
+	resp.Release()
+	if body := resp.Body(); body != nil {
+		t.Errorf("Body() after Release = %q, want nil", body)
+	}
+	resp.Release()
+	var nilResp *Response
+	nilResp.Release()
+}
+
+// benchmarkResponseBody 是基准测试使用的 64KB 响应体
+var benchmarkResponseBody = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+func BenchmarkReadBody(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, err := readBodyBuffer(io.NopCloser(bytes.NewReader(benchmarkResponseBody)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			putBuffer(buf)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := io.ReadAll(bytes.NewReader(benchmarkResponseBody)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestResponseJSONP(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"wrapped", `cb({"name":"a"})`, "a", false},
+		{"trailing semicolon", `callback({"name":"b"});`, "b", false},
+		{"dotted callback and whitespace", " jQuery123.cb ( {\"name\":\"c\"} ) ;\n", "c", false},
+		{"comment prefix", `/**/cb({"name":"d"})`, "d", false},
+		{"parenthesis in payload", `cb({"name":"(e)"})`, "(e)", false},
+		{"unwrapped", `{"name":"f"}`, "f", false},
+		{"not an identifier", `1cb({"name":"g"})`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.body)
+			})
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v struct {
+				Name string `json:"name"`
+			}
+			err = resp.JSONP(&v)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("JSONP(%q) returned no error", tt.body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Name != tt.want {
+				t.Errorf("name %q, want %q", v.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseDrainReusesConnection(t *testing.T) {
+	payload := strings.Repeat("partially read ", 8<<10)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	})
+	var reused []bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) },
+	})
+	for i := 0; i < 3; i++ {
+		resp, err := c.R().SetContext(ctx).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		head := make([]byte, 16)
+		if _, err := io.ReadFull(resp.RawBody(), head); err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Drain(); err != nil {
+			t.Fatalf("Drain after a partial read: %v", err)
+		}
+	}
+	if len(reused) != 3 || reused[0] || !reused[1] || !reused[2] {
+		t.Errorf("connection reuse %v, want [false true true]", reused)
+	}
+}
+
+func TestResponseDrainAfterBody(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "cached body")
+	})
+	tests := []struct {
+		name    string
+		consume func(*Response)
+	}{
+		{"after Body", func(r *Response) { r.Body() }},
+		{"after Release", func(r *Response) { r.Body(); r.Release() }},
+		{"twice", func(r *Response) { r.Drain() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.consume(resp)
+			if err := resp.Drain(); err != nil {
+				t.Errorf("Drain returned %v", err)
+			}
+		})
+	}
+
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body()
+	resp.Drain()
+	if got := resp.String(); got != "cached body" {
+		t.Errorf("body after Drain %q, want the cached body", got)
+	}
+}
+
+func TestResponseCopyHeadersTo(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Set-Cookie", "a=1")
+		h.Add("Set-Cookie", "b=2")
+		h.Add("X-Multi", "one")
+		h.Add("X-Multi", "two")
+		h.Set("Connection", "X-Private, keep-alive")
+		h.Set("X-Private", "secret")
+		h.Set("Keep-Alive", "timeout=5")
+		h.Set("Proxy-Authenticate", "Basic")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetHeader("X-Private") == "" || resp.GetHeader("Connection") == "" {
+		t.Fatalf("server headers missing from the response: %v", resp.Header())
+	}
+	dst := http.Header{"X-Multi": {"zero"}}
+	resp.CopyHeadersTo(dst)
+	if got, want := dst.Values("Set-Cookie"), []string{"a=1", "b=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Set-Cookie %v, want %v", got, want)
+	}
+	if got, want := dst.Values("X-Multi"), []string{"zero", "one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("X-Multi %v, want %v", got, want)
+	}
+	for _, key := range []string{"Connection", "X-Private", "Keep-Alive", "Proxy-Authenticate"} {
+		if values := dst.Values(key); len(values) != 0 {
+			t.Errorf("hop-by-hop header %s copied: %v", key, values)
+		}
+	}
+	if dst.Get("Content-Length") == "" {
+		t.Error("end-to-end header Content-Length was not copied")
+	}
+}
+
+func TestResponseScanMany(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"user":{"name":"alice","age":30,"tags":["a","b"]},"meta":{"active":true,"score":9.5},"items":[{"id":1},{"id":2}]}`)
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		name   string
+		age    int
+		tags   []string
+		active bool
+		score  float64
+		second struct {
+			ID int `json:"id"`
+		}
+	)
+	err = resp.ScanMany(map[string]interface{}{
+		"user.name":   &name,
+		"user.age":    &age,
+		"user.tags":   &tags,
+		"meta.active": &active,
+		"meta.score":  &score,
+		"items.1":     &second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "alice" || age != 30 || !reflect.DeepEqual(tags, []string{"a", "b"}) || !active || score != 9.5 || second.ID != 2 {
+		t.Errorf("scanned name=%q age=%d tags=%v active=%v score=%v second=%+v", name, age, tags, active, score, second)
+	}
+
+	var missing, wrongType int
+	var found string
+	err = resp.ScanMany(map[string]interface{}{
+		"user.missing": &missing,
+		"user.name":    &wrongType,
+		"user.tags.0":  &found,
+	})
+	if err == nil {
+		t.Fatal("ScanMany returned no error for failed paths")
+	}
+	for _, want := range []string{`"user.missing" not found`, `path "user.name"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if found != "a" {
+		t.Errorf("successful path was not assigned alongside failures: %q", found)
+	}
+}
+
+// gzipBytes 返回 data 的 gzip 压缩结果
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResponseJSONGzip(t *testing.T) {
+	payload := gzipBytes(t, []byte(`{"name":"gzipped","count":3}`))
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			w.Header().Set("Content-Type", ContentTypeStream)
+			w.Write(payload)
+		case "/double":
+			// 应用层压缩后再进行传输层压缩
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes(t, payload))
+		default:
+			io.WriteString(w, `{"name":"plain"}`)
+		}
+	})
+	for _, path := range []string{"/app", "/double"} {
+		resp, err := c.R().Execute(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}
+		if err := resp.JSONGzip(&v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if v.Name != "gzipped" || v.Count != 3 {
+			t.Errorf("%s: decoded %+v", path, v)
+		}
+	}
+
+	resp, err := c.R().Execute("/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]string
+	if err := resp.JSONGzip(&v); err == nil || !strings.Contains(err.Error(), "decompress gzip") {
+		t.Errorf("JSONGzip of an uncompressed body returned %v, want a decompression error", err)
+	}
+}
+
+func TestResponseAgeAndIsStale(t *testing.T) {
+	clk := useFakeClock(t)
+	received := clk.Now()
+	date := func(d time.Duration) string { return received.Add(-d).Format(http.TimeFormat) }
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		age      time.Duration
+		ageErr   bool
+		maxAge   time.Duration
+		stale    bool
+		advanced time.Duration
+	}{
+		{"age header", map[string]string{"Age": "30", "Date": date(0)}, 30 * time.Second, false, time.Minute, false, 0},
+		{"date only", map[string]string{"Date": date(time.Minute)}, time.Minute, false, 2 * time.Minute, false, 0},
+		{"larger of age and date", map[string]string{"Age": "10", "Date": date(time.Minute)}, time.Minute, false, time.Minute, true, 0},
+		{"time since receipt", map[string]string{"Age": "30", "Date": date(0)}, 45 * time.Second, false, time.Minute, false, 15 * time.Second},
+		{"cache-control max-age fresh", map[string]string{"Age": "60", "Cache-Control": "public, max-age=120"}, time.Minute, false, 0, false, 0},
+		{"cache-control max-age stale", map[string]string{"Age": "60", "Cache-Control": "max-age=30"}, time.Minute, false, time.Hour, true, 0},
+		{"no-cache", map[string]string{"Age": "0", "Cache-Control": "no-cache, max-age=600"}, 0, false, time.Hour, true, 0},
+		{"no age or date", map[string]string{}, 0, true, time.Hour, true, 0},
+		{"invalid age", map[string]string{"Age": "-5"}, 0, true, time.Hour, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				// 阻止服务端自动添加使用真实时间的 Date 头部
+				w.Header()["Date"] = nil
+				for key, value := range tt.headers {
+					w.Header().Set(key, value)
+				}
+			})
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			clk.Advance(tt.advanced)
+			defer clk.Advance(-tt.advanced)
+			age, err := resp.Age()
+			if tt.ageErr {
+				if err == nil {
+					t.Errorf("Age() = %v, want an error", age)
+				}
+			} else if err != nil || age != tt.age {
+				t.Errorf("Age() = %v, %v, want %v", age, err, tt.age)
+			}
+			if got := resp.IsStale(tt.maxAge); got != tt.stale {
+				t.Errorf("IsStale(%v) = %v, want %v", tt.maxAge, got, tt.stale)
+			}
+		})
+	}
+}
+
+func TestHEADResponseBodyIgnored(t *testing.T) {
+	// 模拟错误地为 HEAD 请求返回响应体且不会结束的服务端
+	var closed int32
+	newClient := func() *Client {
+		c := NewClient().SetBaseURL("http://head.example")
+		c.Logger = discardLogger{}
+		c.Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				io.WriteString(pw, "unexpected body")
+				if req.Method != http.MethodHead {
+					pw.Close()
+				}
+			}()
+			return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: readCloserFunc{pr, func() error {
+				atomic.StoreInt32(&closed, 1)
+				return pr.Close()
+			}}, Request: req}, nil
+		})
+		return c
+	}
+
+	c := newClient()
+	resp, err := c.R().SetMethod(http.MethodHead).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan []byte)
+	go func() { done <- resp.Body() }()
+	select {
+	case body := <-done:
+		if len(body) != 0 {
+			t.Errorf("HEAD response body %q, want empty", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Body() blocked reading a HEAD response body")
+	}
+	if atomic.LoadInt32(&closed) == 0 {
+		t.Error("ignored HEAD response body was not closed")
+	}
+
+	resp, err = c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "unexpected body" {
+		t.Errorf("GET body %q, want it read as usual", got)
+	}
+
+	c = newClient().SetDisableResponseBodyForHEAD(false)
+	resp, err = c.R().SetMethod(http.MethodHead).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.RawBody(), int64(len("unexpected body"))))
+	if err != nil || string(body) != "unexpected body" {
+		t.Errorf("HEAD body with the safeguard disabled %q, %v, want it readable", body, err)
+	}
+}
+
+// readCloserFunc 使用自定义的 Close 函数包装 Reader
+type readCloserFunc struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloserFunc) Close() error { return r.close() }
+
+func TestResponseRaw(t *testing.T) {
+	c := newTLSTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Raw", "1")
+	})
+	resp, err := c.R().Execute("/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := resp.Raw()
+	if raw == nil || raw.TLS == nil {
+		t.Fatal("Raw().TLS is nil for an HTTPS request")
+	}
+	if !raw.TLS.HandshakeComplete || len(raw.TLS.PeerCertificates) == 0 {
+		t.Errorf("unexpected TLS state: %+v", raw.TLS)
+	}
+	if raw.Request == nil || raw.Request.URL.Path != "/raw" {
+		t.Errorf("Raw().Request = %v, want the request for /raw", raw.Request)
+	}
+	if raw.Header.Get("X-Raw") != "1" || raw.StatusCode != resp.StatusCode() {
+		t.Errorf("Raw() header %v status %d disagree with the wrapper", raw.Header, raw.StatusCode)
+	}
+	empty := &Response{}
+	if empty.Raw() != nil {
+		t.Error("Raw() of a response without an underlying response is not nil")
+	}
+}
+
+func TestResponseBodyConsumedSequences(t *testing.T) {
+	const payload = "single use body"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	})
+	stream := func(r *Response) error {
+		body, err := r.Stream()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		_, err = io.ReadAll(body)
+		return err
+	}
+	tee := func(r *Response) error {
+		_, err := io.ReadAll(r.TeeBody(io.Discard))
+		return err
+	}
+	body := func(r *Response) error {
+		if r.Body() == nil {
+			return r.Err
+		}
+		return nil
+	}
+	save := func(r *Response) error {
+		return r.SaveToFile(filepath.Join(t.TempDir(), "body"))
+	}
+
+	tests := []struct {
+		name          string
+		first, second func(*Response) error
+		wantErr       bool
+	}{
+		{"Body after Stream", stream, body, true},
+		{"SaveToFile after Stream", stream, save, true},
+		{"Stream after Stream", stream, stream, true},
+		{"TeeBody after Stream", stream, tee, true},
+		{"Body after TeeBody", tee, body, true},
+		{"SaveToFile after TeeBody", tee, save, true},
+		{"TeeBody after TeeBody", tee, tee, true},
+		{"Stream after Body", body, stream, false},
+		{"TeeBody after Body", body, tee, false},
+		{"SaveToFile after Body", body, save, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.first(resp); err != nil {
+				t.Fatalf("first call: %v", err)
+			}
+			err = tt.second(resp)
+			if tt.wantErr && !errors.Is(err, ErrBodyConsumed) {
+				t.Errorf("got error %v, want ErrBodyConsumed", err)
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("got error %v, want the cached body", err)
+			}
+		})
+	}
+}
+
+func TestResponseTeeBodyClosesBody(t *testing.T) {
+	var reused []bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) },
+	})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("tee ", 4096))
+	})
+	for i := 0; i < 2; i++ {
+		resp, err := c.R().SetContext(ctx).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var captured bytes.Buffer
+		if _, err := io.Copy(io.Discard, resp.TeeBody(&captured)); err != nil {
+			t.Fatal(err)
+		}
+		if captured.Len() != 4*4096 {
+			t.Errorf("captured %d bytes, want %d", captured.Len(), 4*4096)
+		}
+	}
+	if len(reused) != 2 || !reused[1] {
+		t.Errorf("connection reuse %v, want the connection reused after TeeBody reached EOF", reused)
+	}
+}
+
+func TestResponseForEachHeader(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Single", "one")
+		w.Header().Add("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		w.Header().Set("Content-Type", "text/plain")
+	})
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	visited := make(map[string][]string)
+	var keys []string
+	resp.ForEachHeader(func(key string, values []string) {
+		keys = append(keys, key)
+		visited[key] = values
+	})
+	if !reflect.DeepEqual(visited, map[string][]string(resp.Header())) {
+		t.Errorf("visited %v, want every header %v", visited, resp.Header())
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("headers visited in order %v, want sorted by name", keys)
+	}
+	if got, want := visited["X-Multi"], []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("X-Multi values %v, want %v", got, want)
+	}
+	if got := resp.HeaderCount(); got != len(keys) {
+		t.Errorf("HeaderCount() = %d, want %d", got, len(keys))
+	}
+}
@@ -0,0 +1,153 @@
+package quicklyHttps
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backoff 决定每次重试前的等待时间，attempt 从 1 开始表示第几次重试，
+// resp 为上一次尝试的响应，传输层出错时为 nil
+type Backoff interface {
+	Next(attempt int, resp *Response) time.Duration
+}
+
+// ConstantBackoff 每次重试等待固定的时间
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+// Next 实现 Backoff 接口
+func (b ConstantBackoff) Next(attempt int, resp *Response) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff 等待时间从 Initial 开始每次增加 Step，Max 大于 0 时不超过 Max
+type LinearBackoff struct {
+	Initial time.Duration
+	Step    time.Duration
+	Max     time.Duration
+}
+
+// Next 实现 Backoff 接口
+func (b LinearBackoff) Next(attempt int, resp *Response) time.Duration {
+	wait := b.Initial + b.Step*time.Duration(attempt-1)
+	if b.Max > 0 && wait > b.Max {
+		wait = b.Max
+	}
+	return wait
+}
+
+// ExponentialBackoff 等待时间从 Min 开始每次翻倍，Max 大于 0 时不超过 Max
+type ExponentialBackoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Next 实现 Backoff 接口
+func (b ExponentialBackoff) Next(attempt int, resp *Response) time.Duration {
+	wait := b.Min
+	for i := 1; i < attempt && (b.Max <= 0 || wait < b.Max); i++ {
+		if wait > math.MaxInt64/2 {
+			// 未设置 Max 时避免溢出为负数
+			return math.MaxInt64
+		}
+		wait *= 2
+	}
+	if b.Max > 0 && wait > b.Max {
+		wait = b.Max
+	}
+	return wait
+}
+
+// sleepContext 经由当前时钟等待指定时间，ctx 被取消时提前返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	return clock().Sleep(ctx, d)
+}
+
+// retryBudgetWindow 重试预算的统计窗口
+const retryBudgetWindow = 10 * time.Second
+
+// retryBudget 限制重试次数占请求总数的比例，避免大面积故障时产生重试风暴
+type retryBudget struct {
+	mu       sync.Mutex
+	ratio    float64
+	resetAt  time.Time
+	requests int
+	retries  int
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio}
+}
+
+// roll 在统计窗口结束后清空计数
+func (b *retryBudget) roll(now time.Time) {
+	if now.After(b.resetAt) {
+		b.requests, b.retries = 0, 0
+		b.resetAt = now.Add(retryBudgetWindow)
+	}
+}
+
+// recordRequest 记录一次新的请求
+func (b *retryBudget) recordRequest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now())
+	b.requests++
+}
+
+// acquire 尝试消耗一次重试额度，预算耗尽时返回 false
+func (b *retryBudget) acquire() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now())
+	if float64(b.retries+1) > b.ratio*float64(b.requests) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// isTimeoutError 判断错误是否由超时引起
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter 解析响应的 Retry-After 头部，支持秒数和 HTTP 日期两种格式
+func retryAfter(resp *Response) (time.Duration, bool) {
+	if resp == nil || resp.Response == nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.GetHeader("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now()); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
@@ -0,0 +1,503 @@
+package quicklyHttps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hijackClose 关闭连接而不返回响应，使客户端得到传输层错误
+func hijackClose(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func TestSetMaxRetryElapsedTime(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		hijackClose(w)
+	})
+	c.RetryMax = 100
+	c.SetBackoff(ConstantBackoff{Interval: 50 * time.Millisecond}).SetMaxRetryElapsedTime(200 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := c.R().Execute(); err == nil {
+		t.Fatal("expected error from always failing server")
+	}
+	elapsed := time.Since(start)
+	if elapsed > time.Second {
+		t.Errorf("retries took %v, want them to stop near 200ms", elapsed)
+	}
+	if n := atomic.LoadInt32(&attempts); n < 2 || n > 5 {
+		t.Errorf("got %d attempts, want 2..5 within the elapsed cap", n)
+	}
+}
+
+func TestSetRetryBudget(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		hijackClose(w)
+	})
+	c.RetryMax = 5
+	c.SetRetryBudget(0.5)
+
+	const requests = 10
+	for i := 0; i < requests; i++ {
+		if _, err := c.R().Execute(); err == nil {
+			t.Fatal("expected error from always failing server")
+		}
+	}
+	// 不限制时共 50 次尝试，预算只允许 requests*0.5 次重试
+	retries := int(atomic.LoadInt32(&attempts)) - requests
+	if retries < 1 || retries > requests/2 {
+		t.Errorf("got %d retries, want 1..%d under the budget", retries, requests/2)
+	}
+}
+
+// fakeClock 只记录等待时长并推进时间，不真实等待
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleeps = append(f.sleeps, d)
+	f.t = f.t.Add(d)
+	return ctx.Err()
+}
+
+// Advance 将假时钟向前推进 d
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t = f.t.Add(d)
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+	f := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	SetClock(f)
+	t.Cleanup(func() { SetClock(nil) })
+	return f
+}
+
+func TestSetClockBackoffDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{"exponential", ExponentialBackoff{Min: 100 * time.Millisecond, Max: time.Second},
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}},
+		{"linear", LinearBackoff{Initial: time.Second, Step: time.Second, Max: 3 * time.Second},
+			[]time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"constant", ConstantBackoff{Interval: time.Minute},
+			[]time.Duration{time.Minute, time.Minute, time.Minute, time.Minute, time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := useFakeClock(t)
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { hijackClose(w) })
+			c.SetRetryMax(6).SetBackoff(tt.backoff)
+
+			start := time.Now()
+			if _, err := c.R().Execute(); err == nil {
+				t.Fatal("expected error from always failing server")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("retries took %v of real time, want no real sleeps", elapsed)
+			}
+			clk.mu.Lock()
+			defer clk.mu.Unlock()
+			if len(clk.sleeps) != len(tt.want) {
+				t.Fatalf("got sleeps %v, want %v", clk.sleeps, tt.want)
+			}
+			for i := range tt.want {
+				if clk.sleeps[i] != tt.want[i] {
+					t.Errorf("sleep %d = %v, want %v", i+1, clk.sleeps[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetClockMaxRetryElapsedTime(t *testing.T) {
+	clk := useFakeClock(t)
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		hijackClose(w)
+	})
+	c.SetRetryMax(100).SetBackoff(ConstantBackoff{Interval: time.Minute}).SetMaxRetryElapsedTime(3 * time.Minute)
+
+	if _, err := c.R().Execute(); err == nil {
+		t.Fatal("expected error from always failing server")
+	}
+	if n := atomic.LoadInt32(&attempts); n > 4 {
+		t.Errorf("got %d attempts, want at most 4 within 3 fake minutes", n)
+	}
+	if got := clk.Now().Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); got > 3*time.Minute {
+		t.Errorf("fake clock advanced %v, want at most the 3m cap", got)
+	}
+}
+
+func TestSetTimeSourceConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetTimeSource(nil) })
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			SetTimeSource(time.Now)
+			SetTimeSource(nil)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := c.R().Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestOnErrorPerAttempt(t *testing.T) {
+	useFakeClock(t)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { hijackClose(w) })
+	var calls int32
+	c.SetRetryMax(4).SetBackoff(ConstantBackoff{Interval: time.Second}).OnError(func(r *Request, err error) {
+		if err == nil {
+			t.Error("OnError called with nil error")
+		}
+		atomic.AddInt32(&calls, 1)
+	})
+	if _, err := c.R().Execute(); err == nil {
+		t.Fatal("expected error from always failing server")
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("OnError called %d times, want 4 (one per attempt)", n)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	ok := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	ok.OnError(func(*Request, error) { atomic.AddInt32(&calls, 1) })
+	if _, err := ok.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("OnError called %d times for a successful request", n)
+	}
+}
+
+func TestSetTimeoutRetryMax(t *testing.T) {
+	useFakeClock(t)
+	slow := func(attempts *int32, release <-chan struct{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(attempts, 1)
+			<-release
+		}
+	}
+
+	tests := []struct {
+		name            string
+		timeoutRetryMax int
+		want            int32
+	}{
+		{"no timeout retries", 0, 1},
+		{"one timeout retry", 1, 2},
+		{"follows RetryMax", -1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			release := make(chan struct{})
+			c := newTestClient(t, slow(&attempts, release)).SetRetryMax(4).SetTimeoutRetryMax(tt.timeoutRetryMax).SetTimeout(50 * time.Millisecond)
+			// 先于关闭测试服务器执行，释放仍在等待的处理函数
+			t.Cleanup(func() { close(release) })
+			_, err := c.R().SetMethod(http.MethodPost).Execute()
+			if !isTimeoutError(err) {
+				t.Errorf("got error %v, want a timeout", err)
+			}
+			if n := atomic.LoadInt32(&attempts); n != tt.want {
+				t.Errorf("server saw %d attempts, want %d", n, tt.want)
+			}
+		})
+	}
+
+	t.Run("status retries unaffected", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}).SetRetryMax(4).SetTimeoutRetryMax(0).SetRetryableStatusCodes(http.StatusServiceUnavailable)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != http.StatusServiceUnavailable {
+			t.Errorf("status %d, want 503", resp.StatusCode())
+		}
+		if n := atomic.LoadInt32(&attempts); n != 4 {
+			t.Errorf("server saw %d attempts, want 4", n)
+		}
+	})
+}
+
+// timeoutErr 模拟 Timeout() 返回 true 的 net.Error
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestIsTimeoutError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("attempt 1: %w", context.DeadlineExceeded), true},
+		{&net.OpError{Op: "dial", Err: timeoutErr{}}, true},
+		{context.Canceled, false},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isTimeoutError(tt.err); got != tt.want {
+			t.Errorf("isTimeoutError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBuiltinBackoffs(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{"constant", ConstantBackoff{Interval: 250 * ms}, []time.Duration{250 * ms, 250 * ms, 250 * ms}},
+		{"linear", LinearBackoff{Initial: 100 * ms, Step: 50 * ms}, []time.Duration{100 * ms, 150 * ms, 200 * ms, 250 * ms}},
+		{"linear capped", LinearBackoff{Initial: 100 * ms, Step: 100 * ms, Max: 250 * ms}, []time.Duration{100 * ms, 200 * ms, 250 * ms, 250 * ms}},
+		{"exponential", ExponentialBackoff{Min: 10 * ms}, []time.Duration{10 * ms, 20 * ms, 40 * ms, 80 * ms, 160 * ms}},
+		{"exponential capped", ExponentialBackoff{Min: 100 * ms, Max: 300 * ms}, []time.Duration{100 * ms, 200 * ms, 300 * ms, 300 * ms}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.backoff.Next(i+1, nil); got != want {
+				t.Errorf("%s: Next(%d) = %v, want %v", tt.name, i+1, got, want)
+			}
+		}
+	}
+	if got := (ExponentialBackoff{Min: time.Second}).Next(200, nil); got != math.MaxInt64 {
+		t.Errorf("uncapped exponential Next(200) = %v, want saturation instead of overflow", got)
+	}
+}
+
+// recordingBackoff 记录每次调用的参数，返回 attempt 秒
+type recordingBackoff struct {
+	attempts []int
+	statuses []int
+}
+
+func (b *recordingBackoff) Next(attempt int, resp *Response) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode()
+	}
+	b.statuses = append(b.statuses, status)
+	return time.Duration(attempt) * time.Second
+}
+
+func TestSetBackoffCustom(t *testing.T) {
+	clk := useFakeClock(t)
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			hijackClose(w)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}).SetRetryMax(4).SetRetryableStatusCodes(http.StatusServiceUnavailable)
+	backoff := &recordingBackoff{}
+	c.SetBackoff(backoff)
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(backoff.attempts, want) {
+		t.Errorf("Next attempts %v, want %v", backoff.attempts, want)
+	}
+	if want := []int{0, 503, 503}; !reflect.DeepEqual(backoff.statuses, want) {
+		t.Errorf("Next saw statuses %v, want %v", backoff.statuses, want)
+	}
+	clk.mu.Lock()
+	defer clk.mu.Unlock()
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(clk.sleeps, want) {
+		t.Errorf("sleeps %v, want %v", clk.sleeps, want)
+	}
+}
+
+func TestExhaustedRetriesJoinAttemptErrors(t *testing.T) {
+	useFakeClock(t)
+	causes := []error{errors.New("dial refused"), errors.New("connection reset"), errors.New("tls handshake failed")}
+	var calls int32
+	c := NewClient().SetBaseURL("http://example.invalid").SetRetryMax(len(causes))
+	c.Logger = discardLogger{}
+	c.Client.Transport = roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, causes[atomic.AddInt32(&calls, 1)-1]
+	})
+
+	_, err := c.R().Execute()
+	if err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	for i, cause := range causes {
+		if !errors.Is(err, cause) {
+			t.Errorf("error does not wrap attempt %d cause %q: %v", i+1, cause, err)
+		}
+		if prefix := fmt.Sprintf("attempt %d: ", i+1); !strings.Contains(err.Error(), prefix+"Get") {
+			t.Errorf("error missing %q: %v", prefix, err)
+		}
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != len(causes) {
+		t.Errorf("error %v does not join %d attempt errors", err, len(causes))
+	}
+}
+
+func TestSetRetryableStatusCodes(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		w.WriteHeader(code)
+	}).SetRetryMax(3).SetBackoff(nil)
+
+	tests := []struct {
+		name     string
+		setup    func()
+		code     int
+		attempts int32
+	}{
+		{"no codes by default", func() {}, http.StatusServiceUnavailable, 1},
+		{"listed code retried", func() { c.SetRetryableStatusCodes(http.StatusServiceUnavailable, http.StatusBadGateway) }, http.StatusBadGateway, 3},
+		{"unlisted code not retried", func() {}, http.StatusInternalServerError, 1},
+		{"added code retried", func() { c.AddRetryableStatusCode(http.StatusTooManyRequests) }, http.StatusTooManyRequests, 3},
+		{"set replaces previous codes", func() { c.SetRetryableStatusCodes(http.StatusInternalServerError) }, http.StatusServiceUnavailable, 1},
+		{"replacement code retried", func() {}, http.StatusInternalServerError, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			atomic.StoreInt32(&attempts, 0)
+			resp, err := c.R().SetQueryParamInt("code", tt.code).Execute()
+			if err != nil && tt.attempts == 1 {
+				t.Fatal(err)
+			}
+			if resp == nil || resp.StatusCode() != tt.code {
+				t.Fatalf("got response %v, want the last %d response", resp, tt.code)
+			}
+			if n := atomic.LoadInt32(&attempts); n != tt.attempts {
+				t.Errorf("status %d: got %d attempts, want %d", tt.code, n, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRequestSetRetryWait(t *testing.T) {
+	clk := useFakeClock(t)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { hijackClose(w) }).
+		SetRetryMax(4).SetBackoff(ConstantBackoff{Interval: time.Minute})
+
+	sleeps := func(req *Request) []time.Duration {
+		clk.mu.Lock()
+		clk.sleeps = nil
+		clk.mu.Unlock()
+		if _, err := req.Execute(); err == nil {
+			t.Fatal("request to a failing server succeeded")
+		}
+		clk.mu.Lock()
+		defer clk.mu.Unlock()
+		return append([]time.Duration(nil), clk.sleeps...)
+	}
+
+	got := sleeps(c.R().SetRetryWait(10*time.Millisecond, 25*time.Millisecond))
+	if want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}; !reflect.DeepEqual(got, want) {
+		t.Errorf("per-request waits %v, want %v", got, want)
+	}
+	got = sleeps(c.R())
+	if want := []time.Duration{time.Minute, time.Minute, time.Minute}; !reflect.DeepEqual(got, want) {
+		t.Errorf("client default waits %v after a per-request override, want %v", got, want)
+	}
+}
+
+func TestSetRetryAfterCap(t *testing.T) {
+	clk := useFakeClock(t)
+	dayLater := clk.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name       string
+		retryAfter string
+		cap        *time.Duration
+		want       time.Duration
+	}{
+		{"default cap", "86400", nil, defaultRetryAfterCap},
+		{"custom cap", "86400", durationPtr(5 * time.Second), 5 * time.Second},
+		{"below cap", "3", durationPtr(5 * time.Second), 3 * time.Second},
+		{"http date capped", dayLater, durationPtr(time.Minute), time.Minute},
+		{"no cap", "86400", durationPtr(0), 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}).SetRetryMax(2).SetRetryableStatusCodes(http.StatusServiceUnavailable)
+			if tt.cap != nil {
+				c.SetRetryAfterCap(*tt.cap)
+			}
+			clk.mu.Lock()
+			clk.sleeps = nil
+			clk.mu.Unlock()
+			resp, err := c.R().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode() != http.StatusOK {
+				t.Fatalf("status %d, want the retry to succeed", resp.StatusCode())
+			}
+			clk.mu.Lock()
+			defer clk.mu.Unlock()
+			if len(clk.sleeps) != 1 || clk.sleeps[0] != tt.want {
+				t.Errorf("waited %v, want [%v]", clk.sleeps, tt.want)
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
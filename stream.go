@@ -0,0 +1,170 @@
+package quicklyHttps
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NDJSON 逐行读取换行分隔的 JSON 响应体，并对每一行调用 fn，空行会被跳过。
+// fn 返回错误或请求的 context 被取消时停止读取，读取结束后关闭响应体。
+func (r *Response) NDJSON(fn func(json.RawMessage) error) error {
+	body, err := r.Stream()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	reader := bufio.NewReader(body)
+	for {
+		if err := r.contextErr(); err != nil {
+			return err
+		}
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if !json.Valid(line) {
+				return fmt.Errorf("invalid JSON line: %s", line)
+			}
+			if fnErr := fn(json.RawMessage(line)); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// StreamTo 将响应体同时写入多个 Writer，例如一边保存文件一边计算摘要，读取结束后关闭响应体。
+func (r *Response) StreamTo(writers ...io.Writer) (int64, error) {
+	body, err := r.Stream()
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(io.MultiWriter(writers...), body)
+}
+
+// contextErr 返回发起请求的 context 的错误，用于在流式读取时响应取消
+func (r *Response) contextErr() error {
+	if r.rawRequest == nil || r.rawRequest.ctx == nil {
+		return nil
+	}
+	return r.rawRequest.ctx.Err()
+}
+
+// SSE 以 Server-Sent Events 方式请求 SetURL 设置的地址（未设置时为 BaseURL），设置 Accept: text/event-stream，
+// 并对每个事件调用 handler，直到服务端关闭连接或请求的 context 被取消。
+// 未指定事件类型时 event 为 "message"，多行 data 以换行连接，流结束时未完成的事件会被丢弃。
+// 无需调用 SetStreamMode：该请求不受总超时限制，客户端的 Timeout 作为等待每次读取的超时，调试日志也不会读取事件流
+func (r *Request) SSE(handler func(event, data string)) error {
+	r.stream = true
+	r.SetAccept(ContentTypeEventStream)
+	response, err := r.Execute()
+	if err != nil {
+		return err
+	}
+	body := response.RawBody()
+	defer body.Close()
+	if !response.IsSuccess() {
+		return fmt.Errorf("unexpected status code for event stream: %d", response.StatusCode())
+	}
+	return response.readEvents(bufio.NewReader(body), handler)
+}
+
+// readEvents 解析 SSE 数据格式
+func (r *Response) readEvents(reader *bufio.Reader, handler func(event, data string)) error {
+	var event string
+	var data []string
+	for {
+		if err := r.contextErr(); err != nil {
+			return err
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				handler(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// 注释行
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// idleTimeout 在流式模式下限制等待响应头和每次读取响应体的时间，超时后取消请求
+type idleTimeout struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+}
+
+// withIdleTimeout 为请求附加可取消的 context，并开始计时等待响应头
+func withIdleTimeout(req *http.Request, timeout time.Duration) (*http.Request, *idleTimeout) {
+	ctx, cancel := context.WithCancel(req.Context())
+	idle := &idleTimeout{timeout: timeout, cancel: cancel, timer: time.AfterFunc(timeout, cancel)}
+	return req.WithContext(ctx), idle
+}
+
+// wrap 停止等待响应头的计时，并将响应体替换为按次读取计时的 Reader
+func (t *idleTimeout) wrap(response *http.Response, err error) *http.Response {
+	t.timer.Stop()
+	if err != nil {
+		t.cancel()
+		return response
+	}
+	response.Body = &idleTimeoutBody{ReadCloser: response.Body, idle: t}
+	return response
+}
+
+// idleTimeoutBody 每次 Read 时重新计时，单次读取超过超时时间则取消请求
+type idleTimeoutBody struct {
+	io.ReadCloser
+	idle      *idleTimeout
+	closeOnce sync.Once
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.idle.timer.Reset(b.idle.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.idle.timer.Stop()
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeOnce.Do(func() {
+		b.idle.timer.Stop()
+		b.idle.cancel()
+	})
+	return err
+}
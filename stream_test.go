@@ -0,0 +1,312 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseNDJSON(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		switch r.URL.Path {
+		case "/events":
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, "{\"id\":%d}\n", i)
+				if i == 2 {
+					io.WriteString(w, "\n")
+				}
+				w.(http.Flusher).Flush()
+			}
+		case "/invalid":
+			io.WriteString(w, "{\"id\":1}\nnot json\n{\"id\":3}\n")
+		case "/endless":
+			io.WriteString(w, "{\"id\":1}\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	type event struct {
+		ID int `json:"id"`
+	}
+	resp, err := c.R().Execute("/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	err = resp.NDJSON(func(line json.RawMessage) error {
+		var e event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		ids = append(ids, e.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("callback saw ids %v, want [1 2 3] with blank lines skipped", ids)
+	}
+
+	resp, err = c.R().Execute("/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errStop := errors.New("stop")
+	calls := 0
+	if err := resp.NDJSON(func(json.RawMessage) error { calls++; return errStop }); !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("got error %v after %d calls, want callback error after 1", err, calls)
+	}
+
+	resp, err = c.R().Execute("/invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	if err := resp.NDJSON(func(json.RawMessage) error { calls++; return nil }); err == nil || calls != 1 {
+		t.Errorf("got error %v after %d calls, want invalid line error after 1", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err = c.R().SetContext(ctx).Execute("/endless")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	err = resp.NDJSON(func(json.RawMessage) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("got error %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
+
+func TestRequestSSE(t *testing.T) {
+	var accept string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		for _, chunk := range []string{
+			": keep-alive comment\n\n",
+			"data: first\n\n",
+			"event: update\ndata: line one\ndata: line two\n\n",
+			"id: 3\r\nevent: done\r\ndata:{\"ok\":true}\r\n\r\n",
+			"data: unterminated",
+		} {
+			io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	var got []string
+	err := c.R().SetURL(c.BaseURL + "/stream").SSE(func(event, data string) {
+		got = append(got, event+"="+data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != ContentTypeEventStream {
+		t.Errorf("Accept = %q, want %s", accept, ContentTypeEventStream)
+	}
+	want := []string{"message=first", "update=line one\nline two", `done={"ok":true}`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events %q, want %q", got, want)
+	}
+}
+
+func TestRequestSSEErrors(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "data: tick\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	if err := c.R().SetURL(c.BaseURL + "/missing").SSE(func(string, string) {}); err == nil {
+		t.Error("expected error for 404 event stream")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := 0
+	err := c.R().SetContext(ctx).SetURL(c.BaseURL + "/stream").SSE(func(string, string) {
+		events++
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) || events != 1 {
+		t.Errorf("got error %v after %d events, want context.Canceled after 1", err, events)
+	}
+}
+
+// slowStream 每隔 interval 写出一个数据块，共写出 n 个
+func slowStream(n int, interval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < n; i++ {
+			io.WriteString(w, "chunk\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+func TestSetStreamMode(t *testing.T) {
+	const chunks = 8
+	want := strings.Repeat("chunk\n", chunks)
+
+	t.Run("total timeout cuts off stream", func(t *testing.T) {
+		c := newTestClient(t, slowStream(chunks, 50*time.Millisecond)).SetTimeout(150 * time.Millisecond).SetRetryMax(1)
+		resp, err := c.R().Execute()
+		if err == nil {
+			var buf bytes.Buffer
+			_, err = resp.StreamTo(&buf)
+		}
+		if err == nil {
+			t.Fatal("stream of 400ms completed within a 150ms total timeout")
+		}
+	})
+
+	t.Run("stream mode keeps stream open", func(t *testing.T) {
+		c := newTestClient(t, slowStream(chunks, 50*time.Millisecond)).SetTimeout(150 * time.Millisecond).SetStreamMode(true)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := resp.StreamTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("streamed %q, want %d chunks", buf.String(), chunks)
+		}
+	})
+
+	t.Run("stream mode enforces per-read timeout", func(t *testing.T) {
+		c := newTestClient(t, slowStream(3, 500*time.Millisecond)).SetTimeout(150 * time.Millisecond).SetStreamMode(true)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := resp.StreamTo(&buf); err == nil {
+			t.Error("stalled stream was not cut off by the per-read timeout")
+		}
+	})
+}
+
+func TestResponseStreamTo(t *testing.T) {
+	payload := strings.Repeat("stream to many writers\n", 4096)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	}).SetStreamMode(true)
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "download.txt")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.New()
+	n, err := resp.StreamTo(file, hash)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) {
+		t.Errorf("StreamTo copied %d bytes, want %d", n, len(payload))
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != payload {
+		t.Errorf("file holds %d bytes, want the %d byte payload", len(saved), len(payload))
+	}
+	sum := sha256.Sum256([]byte(payload))
+	if got, want := hex.EncodeToString(hash.Sum(nil)), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("hash writer got %s, want %s", got, want)
+	}
+}
+
+func TestRequestSSEWithoutStreamMode(t *testing.T) {
+	firstSeen := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		// 客户端收到第一个事件后才继续，调试日志提前读取整个事件流时这里会超时
+		select {
+		case <-firstSeen:
+		case <-time.After(2 * time.Second):
+			return
+		}
+		for i := 0; i < 4; i++ {
+			time.Sleep(100 * time.Millisecond)
+			fmt.Fprintf(w, "data: tick %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}).SetDebug(true).SetTimeout(250 * time.Millisecond)
+
+	var got []string
+	err := c.R().SSE(func(event, data string) {
+		if data == "first" {
+			close(firstSeen)
+		}
+		got = append(got, data)
+	})
+	if err != nil {
+		t.Fatalf("event stream longer than the client timeout failed: %v", err)
+	}
+	if want := "first|tick 0|tick 1|tick 2|tick 3"; strings.Join(got, "|") != want {
+		t.Errorf("events %q, want %s", got, want)
+	}
+
+	// 同一客户端的普通请求仍受总超时限制
+	slow := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "slow")
+		time.Sleep(200 * time.Millisecond)
+	})
+	c.SetBaseURL(slow.BaseURL).SetRetryMax(1)
+	if resp, err := c.R().Execute(); err == nil && resp.String() == "slow" && resp.Err == nil {
+		t.Error("regular request was not bound by the total timeout")
+	}
+}
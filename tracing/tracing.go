@@ -0,0 +1,66 @@
+// Package tracing 为 quicklyHttps 提供 OpenTelemetry 集成，注入 W3C traceparent/tracestate 请求头并记录客户端 span。
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/catnovel/quicklyHttps"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/catnovel/quicklyHttps/tracing"
+
+// propagator 固定使用 W3C Trace Context 格式
+var propagator = propagation.TraceContext{}
+
+// SetOTelPropagation 启用或关闭 OpenTelemetry 集成，启用后从请求的 context 中读取 span，
+// 注入 traceparent/tracestate 请求头，并使用全局 TracerProvider 为每次请求记录客户端 span
+func SetOTelPropagation(c *quicklyHttps.Client, enable bool) *quicklyHttps.Client {
+	current, wrapped := c.Client.Transport.(*transport)
+	switch {
+	case enable && !wrapped:
+		c.Client.Transport = &transport{base: c.Client.Transport}
+	case !enable && wrapped:
+		c.Client.Transport = current.base
+	}
+	return c
+}
+
+// transport 记录客户端 span 并注入追踪请求头的 RoundTripper
+type transport struct {
+	base http.RoundTripper
+}
+
+// Unwrap 返回被包装的 RoundTripper，使客户端的传输层设置仍然生效
+func (t *transport) Unwrap() http.RoundTripper {
+	return t.base
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tracer := otel.GetTracerProvider().Tracer(instrumentationName)
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.Redacted()),
+		))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
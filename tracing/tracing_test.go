@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/catnovel/quicklyHttps"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetOTelPropagation(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	c := SetOTelPropagation(quicklyHttps.NewClient().SetBaseURL(srv.URL), true)
+	if _, err := c.R().SetContext(ctx).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(traceparent, "00-"+traceID.String()+"-") || !strings.HasSuffix(traceparent, "-01") {
+		t.Errorf("traceparent = %q, want trace %s sampled", traceparent, traceID)
+	}
+
+	traceparent = ""
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if traceparent != "" {
+		t.Errorf("traceparent = %q without a span in context", traceparent)
+	}
+
+	// 重复启用不会重复包装，关闭后恢复原来的 Transport
+	SetOTelPropagation(c, true)
+	if _, ok := c.Client.Transport.(*transport).base.(*transport); ok {
+		t.Error("transport wrapped twice")
+	}
+	SetOTelPropagation(c, false)
+	if _, ok := c.Client.Transport.(*transport); ok {
+		t.Fatal("transport still wrapped after disabling")
+	}
+	traceparent = ""
+	if _, err := c.R().SetContext(ctx).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if traceparent != "" {
+		t.Errorf("traceparent = %q after disabling propagation", traceparent)
+	}
+}
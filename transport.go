@@ -2,8 +2,13 @@ package quicklyHttps
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"runtime"
 	"time"
 )
@@ -12,7 +17,8 @@ func transportDialContext(dialer *net.Dialer) func(context.Context, string, stri
 	return dialer.DialContext
 }
 
-func createTransport(localAddr net.Addr) *http.Transport {
+// newDialer 创建默认配置的 Dialer，localAddr 不为空时绑定出站地址
+func newDialer(localAddr net.Addr) *net.Dialer {
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
@@ -20,9 +26,13 @@ func createTransport(localAddr net.Addr) *http.Transport {
 	if localAddr != nil {
 		dialer.LocalAddr = localAddr
 	}
+	return dialer
+}
+
+func createTransport(localAddr net.Addr) *http.Transport {
 	return &http.Transport{
 		Proxy:                 http.ProxyFromEnvironment,
-		DialContext:           transportDialContext(dialer),
+		DialContext:           transportDialContext(newDialer(localAddr)),
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
@@ -31,3 +41,136 @@ func createTransport(localAddr net.Addr) *http.Transport {
 		MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
 	}
 }
+
+// transport 返回底层的 *http.Transport，自定义的 RoundTripper 无法配置时返回 nil，
+// 实现了 Unwrap() http.RoundTripper 的包装层会被逐层展开
+func (c *Client) transport() *http.Transport {
+	rt := c.Client.Transport
+	for {
+		wrapper, ok := rt.(interface{ Unwrap() http.RoundTripper })
+		if !ok {
+			break
+		}
+		rt = wrapper.Unwrap()
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		c.logger().Error("transport is not *http.Transport, setting ignored", "transport", fmt.Sprintf("%T", c.Client.Transport))
+		return nil
+	}
+	return t
+}
+
+// SetDisableCompression 禁用传输层压缩，不再自动添加 Accept-Encoding: gzip，
+// 也不会自动解压响应体，适合需要获取原始压缩数据的场景
+func (c *Client) SetDisableCompression(disable bool) *Client {
+	if t := c.transport(); t != nil {
+		t.DisableCompression = disable
+	}
+	return c
+}
+
+// SetLocalAddr 设置出站连接绑定的本地地址，用于多网卡或多 IP 的主机
+func (c *Client) SetLocalAddr(addr net.Addr) *Client {
+	if t := c.transport(); t != nil {
+		t.DialContext = transportDialContext(newDialer(addr))
+	}
+	return c
+}
+
+// SetIdleConnTimeout 设置空闲连接的最长保持时间，超过后连接会被关闭
+func (c *Client) SetIdleConnTimeout(timeout time.Duration) *Client {
+	if t := c.transport(); t != nil {
+		t.IdleConnTimeout = timeout
+	}
+	return c
+}
+
+// CloseIdleConnections 关闭当前所有空闲的连接，用于优雅退出或测试清理，不会中断正在使用的连接
+func (c *Client) CloseIdleConnections() {
+	c.Client.CloseIdleConnections()
+}
+
+// SetResponseHeaderTimeout 设置请求发送完成后等待响应头的最长时间，超时则请求失败，
+// 与总超时不同，不限制读取响应体的耗时，0 表示不限制
+func (c *Client) SetResponseHeaderTimeout(timeout time.Duration) *Client {
+	if t := c.transport(); t != nil {
+		t.ResponseHeaderTimeout = timeout
+	}
+	return c
+}
+
+// SetMaxResponseHeaderBytes 设置允许的响应头最大字节数，超出时请求返回错误，0 表示使用默认限制
+func (c *Client) SetMaxResponseHeaderBytes(n int64) *Client {
+	if t := c.transport(); t != nil {
+		t.MaxResponseHeaderBytes = n
+	}
+	return c
+}
+
+// SetForceHTTP1 设置是否强制使用 HTTP/1.1，用于与 HTTP/2 不兼容的服务器，需在发出请求前调用
+func (c *Client) SetForceHTTP1(force bool) *Client {
+	if t := c.transport(); t != nil {
+		t.ForceAttemptHTTP2 = !force
+		if force {
+			t.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+		} else {
+			t.TLSNextProto = nil
+		}
+	}
+	return c
+}
+
+// tlsConfig 返回底层 Transport 的 TLS 配置，未设置时创建一个新的配置
+func (c *Client) tlsConfig() *tls.Config {
+	t := c.transport()
+	if t == nil {
+		return nil
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
+
+// SetTLSVersion 限制 TLS 协议版本范围，如 tls.VersionTLS12，为 0 时表示不限制对应的下限或上限
+func (c *Client) SetTLSVersion(min, max uint16) *Client {
+	if config := c.tlsConfig(); config != nil {
+		config.MinVersion = min
+		config.MaxVersion = max
+	}
+	return c
+}
+
+// SetClientCert 从 PEM 文件加载客户端证书和私钥，用于双向 TLS 认证，可多次调用添加多个证书
+func (c *Client) SetClientCert(certFile, keyFile string) *Client {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		c.logger().Error("failed to load client certificate", "error", err)
+		return c
+	}
+	if config := c.tlsConfig(); config != nil {
+		config.Certificates = append(config.Certificates, cert)
+	}
+	return c
+}
+
+// SetRootCAsFromFile 从 PEM 文件加载 CA 证书，用于校验服务端证书，设置后不再使用系统根证书
+func (c *Client) SetRootCAsFromFile(path string) *Client {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.logger().Error("failed to read root CA file", "error", err)
+		return c
+	}
+	config := c.tlsConfig()
+	if config == nil {
+		return c
+	}
+	if config.RootCAs == nil {
+		config.RootCAs = x509.NewCertPool()
+	}
+	if !config.RootCAs.AppendCertsFromPEM(data) {
+		c.logger().Error("failed to load root CA file", "error", errors.New("no certificates found in "+path))
+	}
+	return c
+}
@@ -0,0 +1,407 @@
+package quicklyHttps
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gzipHandler 在客户端接受 gzip 时返回 gzip 压缩的 payload
+func gzipHandler(payload string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(payload))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(payload))
+		gz.Close()
+	}
+}
+
+func TestSetDisableCompression(t *testing.T) {
+	const payload = "hello compressed world"
+
+	t.Run("default decompresses", func(t *testing.T) {
+		c := newTestClient(t, gzipHandler(payload))
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(resp.Body()); got != payload {
+			t.Errorf("body = %q, want %q", got, payload)
+		}
+	})
+
+	t.Run("disabled omits Accept-Encoding", func(t *testing.T) {
+		var acceptEncoding string
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			acceptEncoding = r.Header.Get("Accept-Encoding")
+			gzipHandler(payload)(w, r)
+		}).SetDisableCompression(true)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acceptEncoding != "" {
+			t.Errorf("Accept-Encoding = %q, want none when compression is disabled", acceptEncoding)
+		}
+		if got := string(resp.Body()); got != payload {
+			t.Errorf("body = %q, want uncompressed %q", got, payload)
+		}
+	})
+
+	t.Run("disabled returns raw gzip bytes", func(t *testing.T) {
+		c := newTestClient(t, gzipHandler(payload)).SetDisableCompression(true)
+		resp, err := c.R().SetHeader("Accept-Encoding", "gzip").Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := resp.Body()
+		if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+			t.Fatalf("body %x is not raw gzip", body)
+		}
+		if got := resp.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip kept", got)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if _, err := out.ReadFrom(gz); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != payload {
+			t.Errorf("decompressed = %q, want %q", out.String(), payload)
+		}
+	})
+}
+
+func TestSetLocalAddr(t *testing.T) {
+	// 127.0.0.2 在 Linux 上默认可用，其他平台不支持绑定时跳过
+	probe, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("cannot bind 127.0.0.2: %v", err)
+	}
+	probe.Close()
+
+	var remote string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+	})
+	c.SetLocalAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.2")})
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "127.0.0.2" {
+		t.Errorf("server saw source %s, want 127.0.0.2", host)
+	}
+}
+
+// newConnStateServer 启动测试服务器，通过 closed 通知服务端观察到的连接关闭
+func newConnStateServer(t *testing.T) (*Client, <-chan struct{}) {
+	t.Helper()
+	closed := make(chan struct{}, 16)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	c := NewClient().SetBaseURL(srv.URL)
+	c.Logger = discardLogger{}
+	return c, closed
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	c, closed := newConnStateServer(t)
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+		t.Fatal("connection closed before CloseIdleConnections")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.CloseIdleConnections()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection not closed by CloseIdleConnections")
+	}
+}
+
+func TestSetIdleConnTimeout(t *testing.T) {
+	c, closed := newConnStateServer(t)
+	c.SetIdleConnTimeout(50 * time.Millisecond)
+	if got := c.transport().IdleConnTimeout; got != 50*time.Millisecond {
+		t.Fatalf("IdleConnTimeout = %v, want 50ms", got)
+	}
+	if _, err := c.R().Execute(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection not closed after IdleConnTimeout")
+	}
+}
+
+func TestSetMaxResponseHeaderBytes(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Huge", strings.Repeat("h", 64<<10))
+	}
+
+	if _, err := newTestClient(t, handler).SetRetryMax(1).R().Execute(); err != nil {
+		t.Fatalf("default limit rejected a 64KB header: %v", err)
+	}
+
+	c := newTestClient(t, handler).SetRetryMax(1).SetMaxResponseHeaderBytes(4 << 10)
+	resp, err := c.R().Execute()
+	if err == nil {
+		t.Fatalf("got status %d, want oversized headers to fail", resp.StatusCode())
+	}
+	if !strings.Contains(err.Error(), "exceeded") {
+		t.Errorf("got error %v, want response headers exceeded", err)
+	}
+}
+
+// newTLSTestClient 启动支持 HTTP/2 的 TLS 测试服务器，返回信任其证书的客户端
+func newTLSTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	c := NewClient().SetBaseURL(srv.URL)
+	c.Logger = discardLogger{}
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	c.tlsConfig().RootCAs = pool
+	return c
+}
+
+func TestSetForceHTTP1(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}
+	tests := []struct {
+		force bool
+		want  string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		c := newTLSTestClient(t, handler).SetForceHTTP1(tt.force)
+		resp, err := c.R().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Raw().Proto != tt.want || resp.String() != tt.want {
+			t.Errorf("force=%v: client proto %s, server proto %s, want %s", tt.force, resp.Raw().Proto, resp.String(), tt.want)
+		}
+	}
+}
+
+// writeClientCert 生成自签名的客户端证书，将证书和私钥以 PEM 格式写入临时目录
+func writeClientCert(t *testing.T, commonName string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, certFile, keyFile
+}
+
+// writePEM 将 der 以指定类型的 PEM 块写入文件
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetClientCertAndRootCAsFromFile(t *testing.T) {
+	clientCert, certFile, keyFile := writeClientCert(t, "test-client")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+
+	c := NewClient().SetBaseURL(srv.URL).SetRootCAsFromFile(caFile).SetClientCert(certFile, keyFile)
+	c.Logger = discardLogger{}
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "test-client" {
+		t.Errorf("server saw client certificate %q, want test-client", got)
+	}
+
+	noCert := NewClient().SetBaseURL(srv.URL).SetRootCAsFromFile(caFile).SetRetryMax(1)
+	noCert.Logger = discardLogger{}
+	if _, err := noCert.R().Execute(); err == nil {
+		t.Error("request without a client certificate succeeded")
+	}
+	untrusted := NewClient().SetBaseURL(srv.URL).SetClientCert(certFile, keyFile).SetRetryMax(1)
+	untrusted.Logger = discardLogger{}
+	if _, err := untrusted.R().Execute(); err == nil {
+		t.Error("request without the custom root CA succeeded")
+	}
+}
+
+func TestSetClientCertInvalidFiles(t *testing.T) {
+	c := NewClient()
+	logger := &captureLogger{}
+	c.Logger = logger
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	c.SetClientCert(missing, missing).SetRootCAsFromFile(missing)
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRootCAsFromFile(notPEM)
+	for _, want := range []string{"failed to load client certificate", "failed to read root CA file", "failed to load root CA file"} {
+		if !strings.Contains(logger.String(), want) {
+			t.Errorf("log %q does not contain %q", logger.String(), want)
+		}
+	}
+	if config := c.tlsConfig(); len(config.Certificates) != 0 {
+		t.Errorf("invalid client certificate was added: %d certificates", len(config.Certificates))
+	}
+}
+
+func TestSetResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+			io.WriteString(w, "late body")
+			return
+		}
+		<-release
+	}).SetResponseHeaderTimeout(50 * time.Millisecond).SetRetryMax(1)
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	_, err := c.R().Execute("/slow-headers")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("got error %v, want a timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v, want it to fail after the header timeout", elapsed)
+	}
+
+	resp, err := c.R().Execute("/slow-body")
+	if err != nil {
+		t.Fatalf("slow body after prompt headers failed: %v", err)
+	}
+	if got := resp.String(); got != "late body" {
+		t.Errorf("body %q, want late body", got)
+	}
+}
+
+func TestSetTLSVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	tests := []struct {
+		name     string
+		min, max uint16
+		ok       bool
+	}{
+		{"unrestricted", 0, 0, true},
+		{"exact match", tls.VersionTLS12, tls.VersionTLS12, true},
+		{"range including server version", tls.VersionTLS11, tls.VersionTLS13, true},
+		{"require newer than server", tls.VersionTLS13, 0, false},
+		{"require older than server", tls.VersionTLS10, tls.VersionTLS11, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient().SetBaseURL(srv.URL).SetRetryMax(1).SetTLSVersion(tt.min, tt.max)
+			c.Logger = discardLogger{}
+			c.tlsConfig().RootCAs = pool
+			resp, err := c.R().Execute()
+			if !tt.ok {
+				if err == nil {
+					t.Error("handshake succeeded outside the allowed TLS versions")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := resp.Raw().TLS.Version; v != tls.VersionTLS12 {
+				t.Errorf("negotiated TLS version %x, want TLS 1.2", v)
+			}
+		})
+	}
+}
@@ -3,19 +3,28 @@ package quicklyHttps
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type HandleRequestResult func(rawRequest *http.Request) *http.Request
 type HandleResponseResult func(rawRequest *Request, response *Response)
+type HandleRequestError func(rawRequest *Request, err error)
+type HandleRedirect func(req *http.Request, via []*http.Request) error
+type ResponseValidator func(response *Response) error
 
 const (
 	defaultHeaderAuthorizationKey = "Authorization"
@@ -28,8 +37,87 @@ const (
 	ContentTypeText               = "text/plain"
 	ContentTypeHtml               = "text/html"
 	ContentTypeMultipart          = "multipart/form-data"
+	ContentTypeEventStream        = "text/event-stream"
+	headerIdempotencyKey          = "Idempotency-Key"
+	headerMethodOverride          = "X-HTTP-Method-Override"
+	redacted                      = "***"
+	defaultResponseCharset        = "gbk"
+	defaultRetryAfterCap          = 2 * time.Minute
 )
 
+// Clock 库内部使用的时钟，用于重试等待、重试预算、熔断以及请求耗时的计时，测试中可替换为假时钟
+type Clock interface {
+	Now() time.Time
+	// Sleep 等待 d，ctx 被取消时提前返回其错误
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// systemClock 基于 time 包的默认时钟
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// funcClock 以函数作为时间来源，等待仍使用系统时钟
+type funcClock func() time.Time
+
+func (f funcClock) Now() time.Time { return f() }
+
+func (funcClock) Sleep(ctx context.Context, d time.Duration) error {
+	return systemClock{}.Sleep(ctx, d)
+}
+
+// clockHolder 包装 Clock 以便存入 atomic.Value（要求每次存入的具体类型一致）
+type clockHolder struct{ Clock }
+
+// currentClock 保存当前使用的时钟，读写均为原子操作，可与进行中的请求并发替换
+var currentClock atomic.Value
+
+// clock 返回当前使用的时钟
+func clock() Clock {
+	if h, ok := currentClock.Load().(clockHolder); ok {
+		return h.Clock
+	}
+	return systemClock{}
+}
+
+// now 返回当前时间
+func now() time.Time {
+	return clock().Now()
+}
+
+// SetClock 替换库内部使用的时钟，重试等待也会经由 c.Sleep，便于在测试中不真实等待即可断言退避时长。
+// 并发安全，传入 nil 恢复为系统时钟
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	currentClock.Store(clockHolder{c})
+}
+
+// SetTimeSource 只替换库内部使用的时间来源，等待仍使用真实计时器，需要同时接管等待时使用 SetClock。
+// 并发安全，传入 nil 恢复为 time.Now
+func SetTimeSource(fn func() time.Time) {
+	if fn == nil {
+		SetClock(nil)
+		return
+	}
+	SetClock(funcClock(fn))
+}
+
+// defaultSensitiveHeaders 默认在日志中脱敏的头部
+var defaultSensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
 // LeveledLogger 接口定义了分级日志记录的方法
 type LeveledLogger interface {
 	Error(msg string, keysAndValues ...interface{})
@@ -39,10 +127,21 @@ type LeveledLogger interface {
 	WithContext(ctx context.Context) LeveledLogger
 }
 
+// Level 表示日志级别，数值越大输出越详细
+type Level int
+
+const (
+	LevelError Level = iota // 只输出错误日志
+	LevelWarn               // 输出警告及以上日志
+	LevelInfo               // 输出信息及以上日志
+	LevelDebug              // 输出全部日志
+)
+
 // standardLogger 是实现 LeveledLogger 接口的默认日志记录器
 type standardLogger struct {
-	ctx  context.Context
-	file *os.File
+	ctx   context.Context
+	file  *os.File
+	level Level
 	*log.Logger
 }
 
@@ -51,6 +150,7 @@ func newStandardLogger() *standardLogger {
 	return &standardLogger{
 		file:   os.Stderr,
 		ctx:    context.Background(),
+		level:  LevelDebug,
 		Logger: log.New(os.Stderr, "", log.LstdFlags),
 	}
 }
@@ -59,23 +159,45 @@ func (l *standardLogger) SetOutput(w io.Writer) {
 	l.Logger.SetOutput(w)
 }
 
+// SetLevel 设置日志级别，低于该级别的日志将被丢弃
+func (l *standardLogger) SetLevel(level Level) {
+	l.level = level
+}
+
+// enabled 判断指定级别的日志是否需要输出
+func (l *standardLogger) enabled(level Level) bool {
+	return level <= l.level
+}
+
 // Error 实现 LeveledLogger 的 Error 方法
 func (l *standardLogger) Error(msg string, keysAndValues ...interface{}) {
+	if !l.enabled(LevelError) {
+		return
+	}
 	l.Printf("[ERROR] "+msg, keysAndValues...)
 }
 
 // Info 实现 LeveledLogger 的 Info 方法
 func (l *standardLogger) Info(msg string, keysAndValues ...interface{}) {
+	if !l.enabled(LevelInfo) {
+		return
+	}
 	l.Printf("[INFO] "+msg, keysAndValues...)
 }
 
 // Debug 实现 LeveledLogger 的 Debug 方法
 func (l *standardLogger) Debug(msg string, keysAndValues ...interface{}) {
+	if !l.enabled(LevelDebug) {
+		return
+	}
 	l.Printf("[DEBUG] "+msg, keysAndValues...)
 }
 
 // Warn 实现 LeveledLogger 的 Warn 方法
 func (l *standardLogger) Warn(msg string, keysAndValues ...interface{}) {
+	if !l.enabled(LevelWarn) {
+		return
+	}
 	l.Printf("[WARN] "+msg, keysAndValues...)
 }
 func (l *standardLogger) WithContext(ctx context.Context) LeveledLogger {
@@ -104,8 +226,79 @@ func parseCookies(cookies string) []*http.Cookie {
 	return result
 }
 
+// isTextContentType 判断媒体类型是否为文本类型，已包含参数的类型不视为可追加字符集
+func isTextContentType(mediaType string) bool {
+	if strings.Contains(mediaType, ";") {
+		return false
+	}
+	mediaType = strings.ToLower(mediaType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// parseSetCookie 解析 Set-Cookie 格式的字符串，保留 Path、Domain、Secure、HttpOnly、SameSite 等属性
+func parseSetCookie(setCookie string) (*http.Cookie, error) {
+	cookies := (&http.Response{Header: http.Header{"Set-Cookie": {setCookie}}}).Cookies()
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("invalid cookie string: %q", setCookie)
+	}
+	return cookies[0], nil
+}
+
+// cookieMatchesURL 根据 cookie 的 Secure、Domain、Path 属性判断是否应随该 URL 发送
+func cookieMatchesURL(cookie *http.Cookie, u *url.URL) bool {
+	if cookie.Secure && u.Scheme != "https" {
+		return false
+	}
+	if cookie.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		host := strings.ToLower(u.Hostname())
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	if cookie.Path != "" {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if !cookiePathMatch(cookie.Path, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// cookiePathMatch 按 RFC 6265 5.1.4 判断请求路径是否匹配 cookie 的 Path：
+// 两者相同，或 cookiePath 是 path 的前缀且以 / 结尾或 path 中紧随其后的字符为 /
+func cookiePathMatch(cookiePath, path string) bool {
+	if path == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
+}
+
+// setAcceptIfAbsent 在未设置 Accept 请求头时设置为指定的类型
+func setAcceptIfAbsent(header http.Header, accept string) {
+	if header.Get("Accept") == "" {
+		header.Set("Accept", accept)
+	}
+}
+
+// truncateBody 截断超过 maxSize 字节的日志内容，maxSize <= 0 时不截断
+func truncateBody(body string, maxSize int) string {
+	if maxSize <= 0 || len(body) <= maxSize {
+		return body
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", body[:maxSize], len(body)-maxSize)
+}
+
 // marshalJSON marshals the input data to a JSON string.
-func marshalJSON(data interface{}) (string, error) {
+func marshalJSON(data interface{}, marshal func(v interface{}) ([]byte, error)) (string, error) {
 	switch v := data.(type) {
 	case string:
 		if json.Valid([]byte(v)) {
@@ -113,7 +306,7 @@ func marshalJSON(data interface{}) (string, error) {
 		}
 		return "", fmt.Errorf("invalid JSON string")
 	default:
-		jsonString, err := json.Marshal(v)
+		jsonString, err := marshal(v)
 		if err != nil {
 			return "", err
 		}
@@ -121,6 +314,39 @@ func marshalJSON(data interface{}) (string, error) {
 	}
 }
 
+// marshalJSONNoEscapeHTML 编码 JSON 时不转义 <、>、& 字符
+func marshalJSONNoEscapeHTML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// newUUID 生成随机的 UUID v4 字符串
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// unmarshalJSONUseNumber 解码 JSON 时将数字保留为 json.Number，避免大整数丢失精度
+func unmarshalJSONUseNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if rest := bytes.TrimSpace(data[decoder.InputOffset():]); len(rest) > 0 {
+		return fmt.Errorf("invalid character %q after top-level value", rest[0])
+	}
+	return nil
+}
+
 // ConvertGBKToUTF8 将 GBK 编码的字节数组转换为 UTF-8 编码
 func ConvertGBKToUTF8(gbkData []byte) ([]byte, error) {
 	reader := transform.NewReader(
@@ -134,6 +360,29 @@ func ConvertGBKToUTF8(gbkData []byte) ([]byte, error) {
 	return utf8Data, nil
 }
 
+// decodeCharset 将指定字符集编码的字节数组转换为 UTF-8 编码
+func decodeCharset(data []byte, charset string) ([]byte, error) {
+	if strings.EqualFold(charset, "gbk") {
+		return ConvertGBKToUTF8(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+	}
+	return enc.NewDecoder().Bytes(data)
+}
+
+// jsonpPattern 匹配 JSONP 包装，回调名允许包含点号，如 jQuery123.cb，支持常见的 /**/ 前缀
+var jsonpPattern = regexp.MustCompile(`(?s)^\s*(?:/\*\*/)?\s*[A-Za-z_$][\w$.]*\s*\((.*)\)\s*;?\s*$`)
+
+// stripJSONP 去除 JSONP 回调包装，不是 JSONP 格式时原样返回
+func stripJSONP(body []byte) []byte {
+	if m := jsonpPattern.FindSubmatch(body); m != nil {
+		return m[1]
+	}
+	return body
+}
+
 // removeEmptyPort strips the empty port in ":port" to ""
 // as mandated by RFC 3986 Section 6.2.3.
 func removeEmptyPort(host string) string {
@@ -147,3 +396,8 @@ func removeEmptyPort(host string) string {
 type User struct {
 	Username, Password string
 }
+
+// KV 键值对，用于按顺序设置请求头和查询参数
+type KV struct {
+	Key, Value string
+}
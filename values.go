@@ -0,0 +1,108 @@
+package quicklyHttps
+
+import (
+	"fmt"
+	urlpkg "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArrayFormat 决定查询参数中数组的编码方式
+type ArrayFormat int
+
+const (
+	ArrayFormatRepeat   ArrayFormat = iota // a=1&a=2
+	ArrayFormatBrackets                    // a[]=1&a[]=2
+	ArrayFormatComma                       // a=1,2
+)
+
+// setArray 按指定格式将数组参数写入 url.Values
+func setArray(values urlpkg.Values, key string, vals []string, format ArrayFormat) {
+	switch format {
+	case ArrayFormatBrackets:
+		values[key+"[]"] = append([]string(nil), vals...)
+	case ArrayFormatComma:
+		values.Set(key, strings.Join(vals, ","))
+	default:
+		values[key] = append([]string(nil), vals...)
+	}
+}
+
+// structToValues 根据结构体字段的 tag 生成 url.Values，
+// tag 格式为 `form:"name,omitempty"`，"-" 表示忽略该字段，切片和数组字段会生成多个同名值
+func structToValues(v interface{}, tagName string) (urlpkg.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("nil %s value", tagName)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s value must be a struct, got %s", tagName, rv.Kind())
+	}
+	values := make(urlpkg.Values)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get(tagName), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr {
+			continue
+		}
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatValue(fv.Index(j)))
+			}
+			continue
+		}
+		values.Add(name, formatValue(fv))
+	}
+	return values, nil
+}
+
+// formatValue 将基础类型的值格式化为字符串
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
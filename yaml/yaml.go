@@ -0,0 +1,24 @@
+// Package yaml 为 quicklyHttps 提供 YAML 请求体与响应体的编解码。
+package yaml
+
+import (
+	"github.com/catnovel/quicklyHttps"
+	"gopkg.in/yaml.v3"
+)
+
+// ContentTypeYAML YAML 请求体的 Content-Type
+const ContentTypeYAML = "application/yaml"
+
+// SetBody 将 v 编码为 YAML 请求体并设置 Content-Type: application/yaml
+func SetBody(r *quicklyHttps.Request, v interface{}) *quicklyHttps.Request {
+	return r.SetBodyMarshal(v, yaml.Marshal, ContentTypeYAML)
+}
+
+// Unmarshal 将 YAML 响应体解码到 v
+func Unmarshal(resp *quicklyHttps.Response, v interface{}) error {
+	body := resp.Body()
+	if resp.Err != nil {
+		return resp.Err
+	}
+	return yaml.Unmarshal(body, v)
+}
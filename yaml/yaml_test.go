@@ -0,0 +1,100 @@
+package yaml
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/catnovel/quicklyHttps"
+)
+
+type container struct {
+	Name  string            `yaml:"name"`
+	Image string            `yaml:"image"`
+	Ports []int             `yaml:"ports,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty"`
+}
+
+type deployment struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name   string            `yaml:"name"`
+		Labels map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Replicas   int         `yaml:"replicas"`
+		Containers []container `yaml:"containers"`
+	} `yaml:"spec"`
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		w.Header().Set("Content-Type", ContentTypeYAML)
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	var want deployment
+	want.Kind = "Deployment"
+	want.Metadata.Name = "web"
+	want.Metadata.Labels = map[string]string{"app": "web", "tier": "frontend"}
+	want.Spec.Replicas = 3
+	want.Spec.Containers = []container{
+		{Name: "nginx", Image: "nginx:1.25", Ports: []int{80, 443}, Env: map[string]string{"MODE": "prod"}},
+		{Name: "sidecar", Image: "envoy:v1"},
+	}
+
+	c := quicklyHttps.NewClient().SetBaseURL(srv.URL)
+	resp, err := SetBody(c.R().SetMethod(http.MethodPut), want).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(contentType, ContentTypeYAML) {
+		t.Errorf("Content-Type %q, want %s", contentType, ContentTypeYAML)
+	}
+	if body := resp.String(); !strings.Contains(body, "replicas: 3") || !strings.Contains(body, "- name: nginx") {
+		t.Errorf("request body is not YAML:\n%s", body)
+	}
+	var got deployment
+	if err := Unmarshal(resp, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip got %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "kind: [unclosed")
+	}))
+	defer srv.Close()
+	c := quicklyHttps.NewClient().SetBaseURL(srv.URL)
+
+	resp, err := c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v deployment
+	if err := Unmarshal(resp, &v); err == nil {
+		t.Error("Unmarshal of invalid YAML returned no error")
+	}
+
+	resp, err = c.R().Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := resp.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if err := Unmarshal(resp, &v); err != quicklyHttps.ErrBodyConsumed {
+		t.Errorf("Unmarshal of a consumed body returned %v, want ErrBodyConsumed", err)
+	}
+}